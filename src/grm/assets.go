@@ -0,0 +1,124 @@
+package main
+
+import (
+	"github.com/google/go-github/github"
+	"grm/config"
+	"regexp"
+	"strings"
+	"fmt"
+)
+
+var (
+	// Checksum files like example.zip.sha256, example.zip.sha256sum, SHA256SUMS or SHA256SUMS.txt
+	checksumFilePattern = regexp.MustCompile(`(?i)(\.sha256(sum)?|^sha256sums(\.txt)?)$`)
+	sha256Pattern       = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+)
+
+// A file attached to a Github Release, the checksum is only known if a checksum file lists it
+type releaseAsset struct {
+	name          string
+	url           string
+	contentType   string
+	size          int
+	downloadCount int
+	sha256        string
+}
+
+func readAssetPattern(name, repository string) (*regexp.Regexp, error) {
+	assetPattern, ok := configuration.NamedSectionGet(name, config.Remote, config.AssetPattern, repository)
+	if !ok || assetPattern == "" {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(assetPattern)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compile regex: %s", assetPattern)
+	}
+	return pattern, nil
+}
+
+// Lists the assets of a release matching the asset pattern, checksum files aren't listed themselves
+// but optionally read to assign the checksums of the listed assets
+func readReleaseAssets(account, repository string, githubAssets []github.ReleaseAsset, pattern *regexp.Regexp,
+	checksums bool, fetcher repositoryFetcher) ([]*releaseAsset, error) {
+
+	assets := make([]*releaseAsset, 0)
+	checksumFiles := make([]github.ReleaseAsset, 0)
+	for _, asset := range githubAssets {
+		if checksumFilePattern.MatchString(asset.GetName()) {
+			checksumFiles = append(checksumFiles, asset)
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(asset.GetName()) {
+			continue
+		}
+
+		assets = append(assets, &releaseAsset{
+			name:          asset.GetName(),
+			url:           asset.GetBrowserDownloadURL(),
+			contentType:   asset.GetContentType(),
+			size:          asset.GetSize(),
+			downloadCount: asset.GetDownloadCount(),
+		})
+	}
+
+	if !checksums || len(assets) == 0 {
+		return assets, nil
+	}
+
+	lookup := make(map[string]*releaseAsset, len(assets))
+	for _, asset := range assets {
+		lookup[asset.name] = asset
+	}
+
+	for _, checksumFile := range checksumFiles {
+		content, err := fetcher.readReleaseAsset(account, repository, checksumFile.GetID())
+		if err != nil {
+			return assets, err
+		}
+
+		for fileName, sum := range parseChecksums(checksumFile.GetName(), string(content)) {
+			if asset, ok := lookup[fileName]; ok {
+				asset.sha256 = strings.ToLower(sum)
+			}
+		}
+	}
+	return assets, nil
+}
+
+// Parses checksum files in the format of sha256sum ("<checksum>  <file>" or "<checksum> *<file>"). Files
+// with a single checksum only, e.g. example.zip.sha256, name the checksummed file by their own name.
+func parseChecksums(checksumFile, content string) map[string]string {
+	checksums := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !sha256Pattern.MatchString(fields[0]) {
+			continue
+		}
+
+		if len(fields) == 1 {
+			fileName := checksumFilePattern.ReplaceAllString(checksumFile, "")
+			if fileName != "" {
+				checksums[fileName] = fields[0]
+			}
+			continue
+		}
+		checksums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+	}
+	return checksums
+}
+
+// Formats a size in bytes human readable, e.g. 4.2 MiB
+func formatSize(size int) string {
+	units := []string{"KiB", "MiB", "GiB"}
+	if size < 1024 {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	value := float64(size) / 1024
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
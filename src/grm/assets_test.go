@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+	"github.com/google/go-github/github"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+)
+
+const (
+	testSumZip = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	testSumTar = "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+)
+
+func TestParseChecksums(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		want    map[string]string
+	}{
+		{"SHA256SUMS", testSumZip + "  example.zip\n" + testSumTar + " *example.tar.gz\n",
+			map[string]string{"example.zip": testSumZip, "example.tar.gz": testSumTar}},
+		{"example.zip.sha256", testSumZip + "\n", map[string]string{"example.zip": testSumZip}},
+		{"example.zip.sha256sum", testSumZip + "  example.zip", map[string]string{"example.zip": testSumZip}},
+		{"SHA256SUMS.txt", "# comment\nnot-a-checksum  example.zip\n\n", map[string]string{}},
+	}
+
+	for _, test := range tests {
+		if got := parseChecksums(test.file, test.content); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: checksums = %v, want %v", test.file, got, test.want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{4404019, "4.2 MiB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GiB"},
+		{5 * 1024 * 1024 * 1024 * 1024, "5120.0 GiB"},
+	}
+
+	for _, test := range tests {
+		if got := formatSize(test.size); got != test.want {
+			t.Errorf("%d: size = %s, want %s", test.size, got, test.want)
+		}
+	}
+}
+
+func TestReadReleaseAssets(t *testing.T) {
+	githubAssets := []github.ReleaseAsset{
+		{ID: github.Int64(1), Name: github.String("example.zip"), Size: github.Int(100)},
+		{ID: github.Int64(2), Name: github.String("example.tar.gz"), Size: github.Int(200)},
+		{ID: github.Int64(3), Name: github.String("example.txt"), Size: github.Int(10)},
+		{ID: github.Int64(4), Name: github.String("SHA256SUMS"), Size: github.Int(150)},
+	}
+	fetcher := &fakeFetcher{assets: map[int64][]byte{
+		4: []byte(testSumZip + "  example.zip\n" + testSumTar + "  example.tar.gz\n"),
+	}}
+
+	tests := []struct {
+		name      string
+		pattern   *regexp.Regexp
+		checksums bool
+		want      map[string]string
+	}{
+		{"all assets", nil, false, map[string]string{"example.zip": "", "example.tar.gz": "", "example.txt": ""}},
+		{"matching assets", regexp.MustCompile(`\.(tar\.gz|zip)$`), false,
+			map[string]string{"example.zip": "", "example.tar.gz": ""}},
+		{"with checksums", regexp.MustCompile(`\.zip$`), true, map[string]string{"example.zip": testSumZip}},
+	}
+
+	for _, test := range tests {
+		assets, err := readReleaseAssets("noctarius", "example", githubAssets, test.pattern, test.checksums, fetcher)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		got := make(map[string]string, len(assets))
+		for _, asset := range assets {
+			got[asset.name] = asset.sha256
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: assets = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRestFetcherReadsReleaseByTag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/api/v3/repos/noctarius/example/releases/tags/v1.1.0" {
+			http.NotFound(writer, request)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write([]byte(`{"tag_name":"v1.1.0","assets":[{"id":1,"name":"example.zip"}]}`))
+	}))
+	defer server.Close()
+	defer setupTestConfiguration(t, "[Remote \"foo\"]\n")()
+
+	client, err := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher, err := newRestFetcher("foo", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release, err := fetcher.readReleaseByTag("noctarius", "example", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(release.Assets) != 1 || release.Assets[0].GetName() != "example.zip" {
+		t.Errorf("assets = %v, want example.zip", release.Assets)
+	}
+
+	// Tags without Github Release are not an error
+	release, err = fetcher.readReleaseByTag("noctarius", "example", "v1.0.0")
+	if err != nil || release != nil {
+		t.Errorf("release of plain tag = %v, %v, want none", release, err)
+	}
+}
+
+func TestChecksumFilePattern(t *testing.T) {
+	for _, name := range []string{"SHA256SUMS", "sha256sums.txt", "example.zip.sha256", "example.zip.SHA256SUM"} {
+		if !checksumFilePattern.MatchString(name) {
+			t.Errorf("%s is not recognized as checksum file", name)
+		}
+	}
+	for _, name := range []string{"example.zip", "sha256.go", "SHA256SUMS.sig"} {
+		if checksumFilePattern.MatchString(name) {
+			t.Errorf("%s is recognized as checksum file", name)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"net/http"
+	"github.com/google/go-github/github"
+	"grm/config"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"encoding/json"
+	"encoding/base64"
+	"crypto/sha256"
+	"crypto"
+	"crypto/rand"
+	"strings"
+)
+
+var authTypes = []string{"basic", "token", "app"}
+
+func isValidAuthType(authType string) bool {
+	for _, t := range authTypes {
+		if t == authType {
+			return true
+		}
+	}
+	return false
+}
+
+// Reads the configured authorization type of a remote definition, definitions
+// configured before the introduction of authorization types use basic auth
+func readAuthType(name string) string {
+	if t, ok := configuration.NamedSectionGet(name, config.Remote, config.AuthType, ""); ok && t != "" {
+		return t
+	}
+	return "basic"
+}
+
+// Creates the transport authorizing the requests of a remote definition, definitions without
+// complete credentials need to run 'grm auth' first
+func newAuthTransport(name string, transport http.RoundTripper) (http.RoundTripper, error) {
+	authType := readAuthType(name)
+
+	readConfig := func(keys ...config.Key) ([]string, error) {
+		values := make([]string, 0, len(keys))
+		for _, key := range keys {
+			value, ok := configuration.NamedSectionGet(name, config.Remote, key, "")
+			if !ok {
+				return nil, fmt.Errorf("Could not retrieve %s from config, please run 'grm auth %s'", key.Name(), name)
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+
+	switch authType {
+	case "basic":
+		values, err := readConfig(config.Username, config.Password, config.Salt)
+		if err != nil {
+			return nil, err
+		}
+		password, err := decrypt(values[1], values[2], machineKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &github.BasicAuthTransport{
+			Username:  values[0],
+			Password:  password,
+			Transport: transport,
+		}, nil
+
+	case "token":
+		values, err := readConfig(config.Token, config.Salt)
+		if err != nil {
+			return nil, err
+		}
+		token, err := decrypt(values[0], values[1], machineKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &tokenTransport{
+			token:     token,
+			transport: transport,
+		}, nil
+
+	case "app":
+		values, err := readConfig(config.AppId, config.InstallationId, config.PrivateKey, config.Salt)
+		if err != nil {
+			return nil, err
+		}
+		appId, err := strconv.ParseInt(values[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse app id: %v", err)
+		}
+		installationId, err := strconv.ParseInt(values[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse installation id: %v", err)
+		}
+		privateKey, err := decrypt(values[2], values[3], machineKey)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := parsePrivateKey([]byte(privateKey))
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse the Github App private key: %v", err)
+		}
+
+		return &appTransport{
+			appId:          appId,
+			installationId: installationId,
+			privateKey:     key,
+			apiUrl:         readApiUrl(name),
+			transport:      transport,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown authorization type '%s', please run 'grm auth %s'", authType, name)
+}
+
+// Authenticates all requests using a personal access token
+type tokenTransport struct {
+	token     string
+	transport http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req2 := cloneRequest(req)
+	req2.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t.token))
+	return t.transport.RoundTrip(req2)
+}
+
+// Authenticates all requests as a Github App installation. The installation token is
+// retrieved by exchanging a signed JWT and automatically refreshed before it expires.
+type appTransport struct {
+	appId          int64
+	installationId int64
+	privateKey     *rsa.PrivateKey
+	apiUrl         string
+	transport      http.RoundTripper
+
+	mutex   sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.installationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	req2 := cloneRequest(req)
+	req2.Header.Set("Authorization", fmt.Sprintf("token %s", token))
+	return t.transport.RoundTrip(req2)
+}
+
+func (t *appTransport) installationToken() (string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// Refresh a minute early to not run into expiration while requests are in flight
+	if t.token != "" && time.Now().Add(time.Minute).Before(t.expires) {
+		return t.token, nil
+	}
+
+	jwt, err := buildAppJwt(t.appId, t.privateKey, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%sapp/installations/%d/access_tokens", t.apiUrl, t.installationId)
+	req, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+	req.Header.Set("Accept", "application/vnd.github.machine-man-preview+json")
+
+	response, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("could not retrieve installation token, status: %s", response.Status)
+	}
+
+	var installationToken struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&installationToken); err != nil {
+		return "", err
+	}
+
+	t.token = installationToken.Token
+	t.expires = installationToken.ExpiresAt
+	return t.token, nil
+}
+
+// Builds the RS256 signed JWT to authenticate as the Github App itself
+func buildAppJwt(appId int64, privateKey *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Backdate the token to compensate for clock drift, Github allows 10 minutes at most
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": appId,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := strings.Join([]string{
+		base64.RawURLEncoding.EncodeToString(header),
+		base64.RawURLEncoding.EncodeToString(claims),
+	}, ".")
+
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+		return rsaKey, nil
+	}
+	return nil, errors.New("private key is not a RSA key")
+}
+
+// Copies the request and its headers, as required by the http.RoundTripper contract
+func cloneRequest(req *http.Request) *http.Request {
+	req2 := new(http.Request)
+	*req2 = *req
+	req2.Header = make(http.Header, len(req.Header))
+	for k, s := range req.Header {
+		req2.Header[k] = append([]string(nil), s...)
+	}
+	return req2
+}
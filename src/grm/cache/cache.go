@@ -0,0 +1,270 @@
+package cache
+
+import (
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"encoding/json"
+	"os"
+	"time"
+	"bytes"
+	"strings"
+	"fmt"
+)
+
+// Immutable resources (commits and tag objects by SHA) are cached permanently and never revalidated
+var permanentPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`/repos/[^/]+/[^/]+/commits/[0-9a-f]{40}$`),
+	regexp.MustCompile(`/repos/[^/]+/[^/]+/git/(commits|tags)/[0-9a-f]{40}$`),
+}
+
+// Headers of cached responses which must not be served from the cache
+var volatileHeaders = []string{
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Reset",
+	"Date",
+}
+
+type Stats struct {
+	Entries   int
+	Permanent int
+	Size      int64
+	Oldest    time.Time
+}
+
+// Caches GET responses on disk and revalidates them using conditional requests
+type Transport struct {
+	dir       string
+	transport http.RoundTripper
+}
+
+type entry struct {
+	Url          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+	Permanent    bool        `json:"permanent"`
+	Stored       time.Time   `json:"stored"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+func CacheDir(homeDir string) string {
+	return filepath.Join(homeDir, "github-release-monitor", "cache")
+}
+
+// Creates a caching transport, the namespace separates cache entries of different credentials
+func NewTransport(homeDir, namespace string, transport http.RoundTripper) *Transport {
+	return &Transport{
+		dir:       filepath.Join(CacheDir(homeDir), namespace),
+		transport: transport,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" {
+		return t.transport.RoundTrip(req)
+	}
+
+	key := buildKey(req)
+	cached := t.load(key)
+
+	if cached != nil && cached.Permanent {
+		return cached.response(req, nil), nil
+	}
+
+	req2 := req
+	if cached != nil {
+		req2 = cloneRequest(req)
+		if cached.ETag != "" {
+			req2.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req2.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	response, err := t.transport.RoundTrip(req2)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode == http.StatusNotModified && cached != nil {
+		response.Body.Close()
+		return cached.response(req, response.Header), nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return response, nil
+	}
+
+	etag := response.Header.Get("ETag")
+	lastModified := response.Header.Get("Last-Modified")
+	permanent := isPermanent(req)
+	if etag == "" && lastModified == "" && !permanent {
+		return response, nil
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	t.store(key, &entry{
+		Url:          req.URL.String(),
+		ETag:         etag,
+		LastModified: lastModified,
+		Permanent:    permanent,
+		Stored:       time.Now().UTC(),
+		Header:       response.Header,
+		Body:         body,
+	})
+
+	return response, nil
+}
+
+func (t *Transport) load(key string) *entry {
+	data, err := ioutil.ReadFile(filepath.Join(t.dir, key))
+	if err != nil {
+		return nil
+	}
+
+	// Corrupted entries are treated as missing and overridden with the next response
+	e := &entry{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil
+	}
+	return e
+}
+
+// Stores a cache entry, failures are ignored since the cache is only an optimization
+func (t *Transport) store(key string, e *entry) {
+	if err := os.MkdirAll(t.dir, os.ModePerm); err != nil {
+		return
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	path := filepath.Join(t.dir, key)
+	// Temporary files left behind by an interrupted run are recognized by their extension
+	tempFile, err := ioutil.TempFile(t.dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tempFile.Write(data)
+	tempFile.Close()
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return
+	}
+	if err := os.Rename(tempFile.Name(), path); err != nil {
+		os.Remove(tempFile.Name())
+	}
+}
+
+func (e *entry) response(req *http.Request, header http.Header) *http.Response {
+	responseHeader := make(http.Header, len(e.Header))
+	for k, v := range e.Header {
+		responseHeader[k] = v
+	}
+	for _, h := range volatileHeaders {
+		responseHeader.Del(h)
+		if header != nil && header.Get(h) != "" {
+			responseHeader.Set(h, header.Get(h))
+		}
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        responseHeader,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// Collects statistics of all cache entries, or only the ones of the given namespace
+func ReadStats(homeDir, namespace string) (*Stats, error) {
+	dir := CacheDir(homeDir)
+	if namespace != "" {
+		dir = filepath.Join(dir, namespace)
+	}
+
+	stats := &Stats{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		// Download verifications are kept next to the namespaces, but are no responses, neither
+		// are temporary files of interrupted writes
+		if info.IsDir() || filepath.Ext(path) == ".json" || filepath.Ext(path) == ".tmp" {
+			return nil
+		}
+
+		stats.Entries++
+		stats.Size += info.Size()
+		if stats.Oldest.IsZero() || info.ModTime().Before(stats.Oldest) {
+			stats.Oldest = info.ModTime()
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		e := &entry{}
+		if json.Unmarshal(data, e) == nil && e.Permanent {
+			stats.Permanent++
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// Removes all cache entries, or only the ones of the given namespace
+func Clear(homeDir, namespace string) error {
+	dir := CacheDir(homeDir)
+	if namespace != "" {
+		dir = filepath.Join(dir, namespace)
+	}
+	return os.RemoveAll(dir)
+}
+
+func isPermanent(req *http.Request) bool {
+	for _, pattern := range permanentPatterns {
+		if pattern.MatchString(req.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Builds the cache key from url and accept header, since different media types return different bodies
+func buildKey(req *http.Request) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s|%s", req.URL.String(), strings.Join(req.Header["Accept"], ","))))
+	return hex.EncodeToString(hash[:])
+}
+
+func cloneRequest(req *http.Request) *http.Request {
+	req2 := new(http.Request)
+	*req2 = *req
+	req2.Header = make(http.Header, len(req.Header))
+	for k, s := range req.Header {
+		req2.Header[k] = append([]string(nil), s...)
+	}
+	return req2
+}
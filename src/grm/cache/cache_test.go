@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"fmt"
+)
+
+const testSha = "0123456789abcdef0123456789abcdef01234567"
+
+// Counts the requests of the test server and the conditional requests among them
+type testServer struct {
+	*httptest.Server
+	requests    int
+	conditional int
+}
+
+func newTestServer(etag string) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		s.requests++
+		if etag != "" && request.Header.Get("If-None-Match") == etag {
+			s.conditional++
+			writer.Header().Set("X-RateLimit-Remaining", fmt.Sprint(100-s.requests))
+			writer.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if etag != "" {
+			writer.Header().Set("ETag", etag)
+		}
+		writer.Header().Set("X-RateLimit-Remaining", fmt.Sprint(100-s.requests))
+		fmt.Fprintf(writer, "response %d", s.requests)
+	}))
+	return s
+}
+
+func newTestTransport(t *testing.T) (*Transport, string) {
+	home, err := ioutil.TempDir("", "grm-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTransport(home, "foo", http.DefaultTransport), home
+}
+
+func get(t *testing.T, transport *Transport, url string) (string, string) {
+	request, _ := http.NewRequest("GET", url, nil)
+	response, err := transport.RoundTrip(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, _ := ioutil.ReadAll(response.Body)
+	return string(body), response.Header.Get("X-RateLimit-Remaining")
+}
+
+func TestTransport(t *testing.T) {
+	tests := []struct {
+		name        string
+		etag        string
+		path        string
+		bodies      []string
+		requests    int
+		conditional int
+	}{
+		{"revalidated by etag", `"v1"`, "/repos/noctarius/example/tags",
+			[]string{"response 1", "response 1", "response 1"}, 3, 2},
+		{"uncacheable without validator", "", "/repos/noctarius/example/tags",
+			[]string{"response 1", "response 2", "response 3"}, 3, 0},
+		{"commits by sha are permanent", "", "/repos/noctarius/example/commits/" + testSha,
+			[]string{"response 1", "response 1", "response 1"}, 1, 0},
+		{"tag objects by sha are permanent", `"v1"`, "/repos/noctarius/example/git/tags/" + testSha,
+			[]string{"response 1", "response 1", "response 1"}, 1, 0},
+	}
+
+	for _, test := range tests {
+		server := newTestServer(test.etag)
+		transport, home := newTestTransport(t)
+
+		for i, want := range test.bodies {
+			if body, _ := get(t, transport, server.URL+test.path); body != want {
+				t.Errorf("%s: body %d = %q, want %q", test.name, i, body, want)
+			}
+		}
+		if server.requests != test.requests || server.conditional != test.conditional {
+			t.Errorf("%s: requests = %d (%d conditional), want %d (%d conditional)", test.name,
+				server.requests, server.conditional, test.requests, test.conditional)
+		}
+
+		server.Close()
+		os.RemoveAll(home)
+	}
+}
+
+func TestTransportRefreshesVolatileHeaders(t *testing.T) {
+	server := newTestServer(`"v1"`)
+	defer server.Close()
+	transport, home := newTestTransport(t)
+	defer os.RemoveAll(home)
+
+	get(t, transport, server.URL+"/repos/noctarius/example/tags")
+	if _, remaining := get(t, transport, server.URL+"/repos/noctarius/example/tags"); remaining != "98" {
+		t.Errorf("rate limit of revalidated response = %s, want the one of the 304 response", remaining)
+	}
+}
+
+func TestReadStats(t *testing.T) {
+	server := newTestServer(`"v1"`)
+	defer server.Close()
+	transport, home := newTestTransport(t)
+	defer os.RemoveAll(home)
+
+	get(t, transport, server.URL+"/repos/noctarius/example/tags")
+	get(t, transport, server.URL+"/repos/noctarius/example/releases")
+	get(t, transport, server.URL+"/repos/noctarius/example/commits/"+testSha)
+
+	// Neither leftovers of interrupted writes nor download verifications are entries
+	dir := filepath.Join(CacheDir(home), "foo")
+	ioutil.WriteFile(filepath.Join(dir, "abc.123.tmp"), []byte("partial"), 0600)
+	ioutil.WriteFile(filepath.Join(CacheDir(home), "downloads.json"), []byte("{}"), 0600)
+
+	tests := []struct {
+		namespace string
+		entries   int
+		permanent int
+	}{
+		{"", 3, 1},
+		{"foo", 3, 1},
+		{"bar", 0, 0},
+	}
+
+	for _, test := range tests {
+		stats, err := ReadStats(home, test.namespace)
+		if err != nil {
+			t.Errorf("%q: %v", test.namespace, err)
+			continue
+		}
+		if stats.Entries != test.entries || stats.Permanent != test.permanent {
+			t.Errorf("%q: stats = %d entries (%d permanent), want %d (%d permanent)", test.namespace,
+				stats.Entries, stats.Permanent, test.entries, test.permanent)
+		}
+		if test.entries > 0 && (stats.Size == 0 || stats.Oldest.IsZero()) {
+			t.Errorf("%q: size %d and oldest entry %s missing", test.namespace, stats.Size, stats.Oldest)
+		}
+	}
+
+	if err := Clear(home, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := ReadStats(home, ""); stats.Entries != 0 {
+		t.Errorf("%d entries after clear", stats.Entries)
+	}
+}
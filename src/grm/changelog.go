@@ -0,0 +1,363 @@
+package main
+
+import (
+	"github.com/google/go-github/github"
+	"grm/config"
+	"strings"
+	"sort"
+	"regexp"
+	"strconv"
+	"fmt"
+)
+
+// Heading of entries without any mapped label
+const otherChangesHeading = "Other Changes"
+
+// Heading of merged pull requests, which carry no labels in commit messages
+const pullRequestsHeading = "Merged Pull Requests"
+
+var changelogSources = []string{"milestone", "pull-requests", "conventional-commits"}
+
+var (
+	mergeCommitPattern        = regexp.MustCompile(`^Merge pull request #(\d+) from ([^/\s]+)\S*[ \t]*(?:\n\s*\n(.*))?`)
+	squashCommitPattern       = regexp.MustCompile(`^(.*) \(#(\d+)\)$`)
+	conventionalCommitPattern = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?: (.+)$`)
+	breakingChangePattern     = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+)
+
+// Conventional Commit types listed if no label mapping is configured
+var conventionalCommitHeadings = []*labelHeading{
+	{label: "breaking", heading: "Breaking Changes"},
+	{label: "feat", heading: "Features"},
+	{label: "fix", heading: "Bug Fixes"},
+	{label: "perf", heading: "Performance Improvements"},
+}
+
+// Changes of a release grouped into sections, entries beyond the per-release
+// limit are only counted and linked
+type changelog struct {
+	sections []*changelogSection
+	more     int
+	moreUrl  string
+	// Servers not paginating comparisons list only part of the commits
+	truncated bool
+	// All pull requests of the milestone, including those beyond the limit
+	pullRequests []*changelogEntry
+}
+
+type changelogSection struct {
+	heading string
+	entries []*changelogEntry
+}
+
+type changelogEntry struct {
+	title       string
+	number      int
+	sha         string
+	url         string
+	pullRequest bool
+	author      string
+	labels      []string
+}
+
+type labelHeading struct {
+	label   string
+	heading string
+}
+
+// Reads the label to heading mappings of a definition, e.g. "bug=Bug Fixes,enhancement=Enhancements".
+// Without mappings, entries are grouped by their first label.
+func readLabelHeadings(name string) []*labelHeading {
+	value, ok := configuration.NamedSectionGet(name, config.Remote, config.ChangelogLabels, "")
+	if !ok || value == "" {
+		return nil
+	}
+
+	headings := make([]*labelHeading, 0)
+	for _, mapping := range strings.Split(value, ",") {
+		parts := strings.SplitN(mapping, "=", 2)
+		label := strings.TrimSpace(parts[0])
+		if label == "" {
+			continue
+		}
+
+		heading := label
+		if len(parts) == 2 && strings.TrimSpace(parts[1]) != "" {
+			heading = strings.TrimSpace(parts[1])
+		}
+		headings = append(headings, &labelHeading{label: strings.ToLower(label), heading: heading})
+	}
+	return headings
+}
+
+func readChangelogSource(name, repository string) (string, error) {
+	changelogSource := "milestone"
+	if s, ok := configuration.NamedSectionGet(name, config.Remote, config.ChangelogSource, repository); ok && s != "" {
+		changelogSource = s
+	}
+
+	for _, source := range changelogSources {
+		if source == changelogSource {
+			return changelogSource, nil
+		}
+	}
+	return "", fmt.Errorf("unknown changelog source: %s", changelogSource)
+}
+
+// Builds the changelog of a release from its milestone or the commits since the previous tag,
+// releases without milestone or previous tag have no changelog
+func readReleaseChangelog(changelogSource, account, repository, repositoryUrl string, release *release,
+	headings []*labelHeading, limit int, fetcher repositoryFetcher) (*changelog, error) {
+
+	if changelogSource == "milestone" {
+		if release.milestone == nil {
+			return nil, nil
+		}
+		entries, err := readMilestoneChanges(account, repository, release.milestone, fetcher)
+		if err != nil {
+			return nil, err
+		}
+		result := buildChangelog(entries, headings, limit, release.milestoneUrl)
+		result.pullRequests = filterPullRequests(entries)
+		return result, nil
+	}
+
+	if release.previousTag == "" {
+		return nil, nil
+	}
+
+	comparison, err := readReleaseComparison(account, repository, release, fetcher)
+	if err != nil {
+		return nil, err
+	}
+	compareUrl := fmt.Sprintf("%s/compare/%s...%s", repositoryUrl, release.previousTag, release.tag)
+
+	var result *changelog
+	if changelogSource == "pull-requests" {
+		entries := parsePullRequests(comparison.Commits, repositoryUrl)
+		result = buildChangelog(entries, []*labelHeading{{heading: pullRequestsHeading}}, limit, compareUrl)
+	} else {
+		if headings == nil {
+			headings = conventionalCommitHeadings
+		}
+		entries := parseConventionalCommits(comparison.Commits, repositoryUrl, headings)
+		result = buildChangelog(entries, headings, limit, compareUrl)
+	}
+
+	result.truncated = len(comparison.Commits) < comparison.GetTotalCommits()
+	return result, nil
+}
+
+// Finds the merged pull requests in the commits, either by their merge commit or by the
+// pull request number appended to squashed commits
+func parsePullRequests(commits []github.RepositoryCommit, repositoryUrl string) []*changelogEntry {
+	entries := make([]*changelogEntry, 0)
+	for _, commit := range commits {
+		message := commit.GetCommit().GetMessage()
+		subject := strings.SplitN(message, "\n", 2)[0]
+
+		if match := mergeCommitPattern.FindStringSubmatch(message); match != nil {
+			number, _ := strconv.Atoi(match[1])
+			title := strings.TrimSpace(strings.SplitN(match[3], "\n", 2)[0])
+			if title == "" {
+				title = subject
+			}
+			entries = append(entries, &changelogEntry{
+				title:       title,
+				number:      number,
+				sha:         commit.GetSHA(),
+				url:         fmt.Sprintf("%s/pull/%d", repositoryUrl, number),
+				pullRequest: true,
+				author:      match[2],
+				labels:      []string{pullRequestsHeading},
+			})
+			continue
+		}
+
+		if match := squashCommitPattern.FindStringSubmatch(subject); match != nil {
+			number, _ := strconv.Atoi(match[2])
+			entries = append(entries, &changelogEntry{
+				title:       match[1],
+				number:      number,
+				sha:         commit.GetSHA(),
+				url:         fmt.Sprintf("%s/pull/%d", repositoryUrl, number),
+				pullRequest: true,
+				author:      commitAuthor(commit),
+				labels:      []string{pullRequestsHeading},
+			})
+		}
+	}
+	return entries
+}
+
+// Parses Conventional Commit messages, using the commit type as label. Breaking changes, marked
+// by "!" or a BREAKING CHANGE footer, are labeled "breaking". Commits with an unmapped type or
+// without Conventional Commit message are left out.
+func parseConventionalCommits(commits []github.RepositoryCommit, repositoryUrl string, headings []*labelHeading) []*changelogEntry {
+	mapped := make(map[string]bool, len(headings))
+	for _, h := range headings {
+		mapped[h.label] = true
+	}
+
+	entries := make([]*changelogEntry, 0)
+	for _, commit := range commits {
+		parts := strings.SplitN(commit.GetCommit().GetMessage(), "\n", 2)
+		subject := parts[0]
+
+		match := conventionalCommitPattern.FindStringSubmatch(subject)
+		if match == nil {
+			continue
+		}
+
+		commitType := strings.ToLower(match[1])
+		// Breaking changes are marked in the subject or by a footer, not by mentions in the description
+		if match[3] == "!" || (len(parts) == 2 && breakingChangePattern.MatchString(parts[1])) {
+			commitType = "breaking"
+		}
+		if !mapped[commitType] {
+			continue
+		}
+
+		title := match[4]
+		if match[2] != "" {
+			title = fmt.Sprintf("%s: %s", match[2], title)
+		}
+
+		entry := &changelogEntry{
+			title:  title,
+			sha:    commit.GetSHA(),
+			url:    fmt.Sprintf("%s/commit/%s", repositoryUrl, commit.GetSHA()),
+			author: commitAuthor(commit),
+			labels: []string{commitType},
+		}
+
+		// Squashed pull requests carry their number at the end of the subject
+		if squashed := squashCommitPattern.FindStringSubmatch(title); squashed != nil {
+			entry.number, _ = strconv.Atoi(squashed[2])
+			entry.title = squashed[1]
+			entry.url = fmt.Sprintf("%s/pull/%d", repositoryUrl, entry.number)
+			entry.pullRequest = true
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Milestones list issues and pull requests alike
+func filterPullRequests(entries []*changelogEntry) []*changelogEntry {
+	pullRequests := make([]*changelogEntry, 0)
+	for _, entry := range entries {
+		if entry.pullRequest {
+			pullRequests = append(pullRequests, entry)
+		}
+	}
+	return pullRequests
+}
+
+// The Github login of the commit author, or the git author name for unknown users
+func commitAuthor(commit github.RepositoryCommit) string {
+	if login := commit.GetAuthor().GetLogin(); login != "" {
+		return login
+	}
+	return commit.GetCommit().GetAuthor().GetName()
+}
+
+// Reads the closed issues and pull requests of a milestone
+func readMilestoneChanges(account, repository string, milestone *github.Milestone, fetcher repositoryFetcher) ([]*changelogEntry, error) {
+	issues, err := fetcher.readMilestoneIssues(account, repository, milestone.GetNumber())
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*changelogEntry, 0, len(issues))
+	for _, issue := range issues {
+		labels := make([]string, 0, len(issue.Labels))
+		for _, label := range issue.Labels {
+			labels = append(labels, label.GetName())
+		}
+
+		entries = append(entries, &changelogEntry{
+			title:       issue.GetTitle(),
+			number:      issue.GetNumber(),
+			url:         issue.GetHTMLURL(),
+			pullRequest: issue.PullRequestLinks != nil,
+			author:      issue.GetUser().GetLogin(),
+			labels:      labels,
+		})
+	}
+	return entries, nil
+}
+
+// Groups the entries by label into sections, ordered like the label headings, and caps the
+// number of listed entries. A limit of 0 lists all entries.
+func buildChangelog(entries []*changelogEntry, headings []*labelHeading, limit int, moreUrl string) *changelog {
+	sections := make([]*changelogSection, 0)
+	lookup := make(map[string]*changelogSection)
+
+	section := func(heading string) *changelogSection {
+		s, ok := lookup[heading]
+		if !ok {
+			s = &changelogSection{heading: heading}
+			lookup[heading] = s
+			sections = append(sections, s)
+		}
+		return s
+	}
+
+	// Sections appear in the order of the mappings, even if entries are found in a different order
+	for _, h := range headings {
+		section(h.heading)
+	}
+
+	for _, entry := range entries {
+		heading := entryHeading(entry, headings)
+		s := section(heading)
+		s.entries = append(s.entries, entry)
+	}
+
+	// Entries without mapped label always come last
+	sort.SliceStable(sections, func(i, j int) bool {
+		return sections[i].heading != otherChangesHeading && sections[j].heading == otherChangesHeading
+	})
+
+	result := &changelog{moreUrl: moreUrl}
+	listed := 0
+	for _, s := range sections {
+		if len(s.entries) == 0 {
+			continue
+		}
+
+		if limit > 0 && listed+len(s.entries) > limit {
+			remaining := limit - listed
+			if remaining < 0 {
+				remaining = 0
+			}
+			result.more += len(s.entries) - remaining
+			s.entries = s.entries[:remaining]
+		}
+		listed += len(s.entries)
+
+		if len(s.entries) > 0 {
+			result.sections = append(result.sections, s)
+		}
+	}
+	return result
+}
+
+func entryHeading(entry *changelogEntry, headings []*labelHeading) string {
+	if headings == nil {
+		if len(entry.labels) > 0 {
+			return entry.labels[0]
+		}
+		return otherChangesHeading
+	}
+
+	for _, h := range headings {
+		for _, label := range entry.labels {
+			if strings.ToLower(label) == h.label {
+				return h.heading
+			}
+		}
+	}
+	return otherChangesHeading
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"testing"
+	"github.com/google/go-github/github"
+	"reflect"
+	"strconv"
+	"fmt"
+	"strings"
+)
+
+const testRepositoryUrl = "https://github.com/noctarius/example"
+
+func TestParsePullRequests(t *testing.T) {
+	commits := []github.RepositoryCommit{
+		testCommit("a1", "octocat", "Octo Cat", "Merge pull request #12 from jdoe/feature\n\nAdd feature"),
+		testCommit("a2", "octocat", "Octo Cat", "Merge pull request #13 from jdoe/untitled"),
+		testCommit("a3", "jdoe", "John Doe", "Fix parsing (#14)\n\n* details"),
+		testCommit("a4", "jdoe", "John Doe", "Plain commit"),
+		testCommit("a5", "", "Jane Roe", "Update docs (#15)"),
+	}
+
+	want := []changelogEntry{
+		{title: "Add feature", number: 12, sha: "a1", url: testRepositoryUrl + "/pull/12", pullRequest: true, author: "jdoe"},
+		{title: "Merge pull request #13 from jdoe/untitled", number: 13, sha: "a2", url: testRepositoryUrl + "/pull/13", pullRequest: true, author: "jdoe"},
+		{title: "Fix parsing", number: 14, sha: "a3", url: testRepositoryUrl + "/pull/14", pullRequest: true, author: "jdoe"},
+		{title: "Update docs", number: 15, sha: "a5", url: testRepositoryUrl + "/pull/15", pullRequest: true, author: "Jane Roe"},
+	}
+
+	entries := parsePullRequests(commits, testRepositoryUrl)
+	if len(entries) != len(want) {
+		t.Fatalf("found %d pull requests, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		entry.labels = nil
+		if !reflect.DeepEqual(*entry, want[i]) {
+			t.Errorf("entry %d = %+v, want %+v", i, *entry, want[i])
+		}
+	}
+}
+
+func TestParseConventionalCommits(t *testing.T) {
+	tests := []struct {
+		message string
+		title   string
+		label   string
+		number  int
+	}{
+		{"feat: add resolver", "add resolver", "feat", 0},
+		{"fix(parser): handle dates", "parser: handle dates", "fix", 0},
+		{"Feat: upper case type", "upper case type", "feat", 0},
+		{"feat!: drop old api", "drop old api", "breaking", 0},
+		{"fix: change defaults\n\nBREAKING CHANGE: defaults differ", "change defaults", "breaking", 0},
+		{"fix: change defaults\n\nDetails\n\nBREAKING-CHANGE: defaults differ", "change defaults", "breaking", 0},
+		{"feat: explain BREAKING CHANGE handling", "explain BREAKING CHANGE handling", "feat", 0},
+		{"fix: handle footers\n\nCommits with BREAKING CHANGE: in the body are breaking", "handle footers", "fix", 0},
+		{"perf: faster scan (#42)", "faster scan", "perf", 42},
+		{"chore: update deps", "", "", 0},
+		{"no conventional commit", "", "", 0},
+	}
+
+	for _, test := range tests {
+		commits := []github.RepositoryCommit{testCommit("abc", "jdoe", "John Doe", test.message)}
+		entries := parseConventionalCommits(commits, testRepositoryUrl, conventionalCommitHeadings)
+
+		if test.label == "" {
+			if len(entries) != 0 {
+				t.Errorf("%q: found %d entries, want none", test.message, len(entries))
+			}
+			continue
+		}
+		if len(entries) != 1 {
+			t.Errorf("%q: found %d entries, want 1", test.message, len(entries))
+			continue
+		}
+
+		entry := entries[0]
+		if entry.title != test.title || entry.labels[0] != test.label || entry.number != test.number {
+			t.Errorf("%q: entry = %q (%s, #%d), want %q (%s, #%d)", test.message,
+				entry.title, entry.labels[0], entry.number, test.title, test.label, test.number)
+		}
+		if test.number == 0 && entry.url != testRepositoryUrl+"/commit/abc" {
+			t.Errorf("%q: url = %s, want the commit url", test.message, entry.url)
+		}
+	}
+}
+
+func TestReadReleaseChangelogTruncated(t *testing.T) {
+	tests := []struct {
+		name      string
+		total     int
+		truncated bool
+	}{
+		{"complete comparison", 2, false},
+		{"comparison cut off by the server", 300, true},
+	}
+
+	for _, test := range tests {
+		fetcher := &fakeFetcher{comparison: &github.CommitsComparison{
+			TotalCommits: github.Int(test.total),
+			Commits: []github.RepositoryCommit{
+				testCommit("a1", "jdoe", "John Doe", "feat: first"),
+				testCommit("a2", "jdoe", "John Doe", "fix: second"),
+			},
+		}}
+		rel := &release{tag: "v1.1.0", previousTag: "v1.0.0"}
+
+		c, err := readReleaseChangelog("conventional-commits", "noctarius", "example", testRepositoryUrl, rel, nil, 0, fetcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.truncated != test.truncated {
+			t.Errorf("%s: truncated = %v, want %v", test.name, c.truncated, test.truncated)
+		}
+		if len(c.sections) != 2 {
+			t.Errorf("%s: %d sections, want 2", test.name, len(c.sections))
+		}
+		if c.moreUrl != testRepositoryUrl+"/compare/v1.0.0...v1.1.0" {
+			t.Errorf("%s: more url = %s", test.name, c.moreUrl)
+		}
+	}
+}
+
+// Describes the sections of a changelog by their heading and entry numbers, e.g. "Bug Fixes: 2,4"
+func describeChangelog(c *changelog) string {
+	sections := make([]string, 0, len(c.sections))
+	for _, s := range c.sections {
+		numbers := make([]string, 0, len(s.entries))
+		for _, entry := range s.entries {
+			numbers = append(numbers, strconv.Itoa(entry.number))
+		}
+		sections = append(sections, fmt.Sprintf("%s: %s", s.heading, strings.Join(numbers, ",")))
+	}
+	return strings.Join(sections, "; ")
+}
+
+func TestBuildChangelog(t *testing.T) {
+	headings := []*labelHeading{
+		{label: "bug", heading: "Bug Fixes"},
+		{label: "enhancement", heading: "Enhancements"},
+		{label: "documentation", heading: "Documentation"},
+	}
+
+	tests := []struct {
+		name     string
+		headings []*labelHeading
+		limit    int
+		want     string
+		more     int
+	}{
+		{"grouped by mapped labels", headings, 0, "Bug Fixes: 2,4; Enhancements: 1; Other Changes: 3,5", 0},
+		{"limit above entries", headings, 10, "Bug Fixes: 2,4; Enhancements: 1; Other Changes: 3,5", 0},
+		{"limit at section boundary", headings, 3, "Bug Fixes: 2,4; Enhancements: 1", 2},
+		{"limit splitting a section", headings, 1, "Bug Fixes: 2", 4},
+		{"limit splitting the last section", headings, 4, "Bug Fixes: 2,4; Enhancements: 1; Other Changes: 3", 1},
+		// Without mappings entries are grouped by their first label as is
+		{"grouped by first label", nil, 0, "enhancement: 1; bug: 2; Bug: 4; question: 5; Other Changes: 3", 0},
+	}
+
+	for _, test := range tests {
+		entries := []*changelogEntry{
+			{number: 1, labels: []string{"enhancement"}},
+			{number: 2, labels: []string{"bug"}},
+			{number: 3},
+			{number: 4, labels: []string{"Bug", "enhancement"}},
+			{number: 5, labels: []string{"question"}},
+		}
+
+		c := buildChangelog(entries, test.headings, test.limit, "https://example.com/more")
+		if got := describeChangelog(c); got != test.want || c.more != test.more {
+			t.Errorf("%s: changelog = %q and %d more, want %q and %d more", test.name, got, c.more, test.want, test.more)
+		}
+	}
+}
+
+func TestReadLabelHeadings(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []labelHeading
+	}{
+		{"", nil},
+		{"bug=Bug Fixes, Enhancement = Enhancements", []labelHeading{{"bug", "Bug Fixes"}, {"enhancement", "Enhancements"}}},
+		// Labels without heading are their own heading, empty mappings are ignored
+		{"bug,,question=", []labelHeading{{"bug", "bug"}, {"question", "question"}}},
+	}
+
+	for _, test := range tests {
+		content := "[Remote \"foo\"]\n"
+		if test.value != "" {
+			content += "changelog-labels=" + test.value + "\n"
+		}
+		restore := setupTestConfiguration(t, content)
+		headings := readLabelHeadings("foo")
+		restore()
+
+		got := make([]labelHeading, 0, len(headings))
+		for _, h := range headings {
+			got = append(got, *h)
+		}
+		if len(got) != len(test.want) || (len(got) > 0 && !reflect.DeepEqual(got, test.want)) {
+			t.Errorf("%q: headings = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
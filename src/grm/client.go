@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"github.com/google/go-github/github"
+	"grm/config"
+	"fmt"
+	"strings"
+	"net/url"
+	"crypto/x509"
+	"io/ioutil"
+	"crypto/tls"
+	"net"
+	"time"
+	"grm/cache"
+)
+
+const defaultApiUrl = "https://api.github.com/"
+
+// Creates the Github client of a remote definition, responses are cached on disk unless disabled
+func newGithubClient(name string, cached bool) (*github.Client, error) {
+	baseTransport, err := newBaseTransport(name)
+	if err != nil {
+		return nil, err
+	}
+	transport := &rateLimitTransport{
+		limiter:   githubRateLimiter,
+		transport: baseTransport,
+	}
+	authTransport, err := newAuthTransport(name, transport)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{Transport: authTransport}
+	if cached {
+		httpClient.Transport = cache.NewTransport(*homeDir, name, authTransport)
+	}
+
+	apiUrl := readApiUrl(name)
+	if apiUrl == defaultApiUrl {
+		return github.NewClient(httpClient), nil
+	}
+
+	uploadUrl := readUploadUrl(name, apiUrl)
+
+	client, err := github.NewEnterpriseClient(apiUrl, uploadUrl, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("could not create Github Enterprise client for '%s': %v", apiUrl, err)
+	}
+	return client, nil
+}
+
+// Reads the configured api url, Github Enterprise Server uses https://<host>/api/v3/
+func readApiUrl(name string) string {
+	apiUrl := defaultApiUrl
+	if u, ok := configuration.NamedSectionGet(name, config.Remote, config.ApiUrl, ""); ok && u != "" {
+		apiUrl = u
+	}
+	if !strings.HasSuffix(apiUrl, "/") {
+		apiUrl = apiUrl + "/"
+	}
+	return apiUrl
+}
+
+// Reads the configured upload url, Github Enterprise Server serves uploads from https://<host>/api/uploads/
+func readUploadUrl(name, apiUrl string) string {
+	if u, ok := configuration.NamedSectionGet(name, config.Remote, config.UploadUrl, ""); ok && u != "" {
+		return u
+	}
+
+	parsed, err := url.Parse(apiUrl)
+	if err != nil || parsed.Host == "" {
+		return apiUrl
+	}
+	return fmt.Sprintf("%s://%s/api/uploads/", parsed.Scheme, parsed.Host)
+}
+
+// Creates the transport for all requests of a remote definition, applying the
+// configured proxy and additional certificate authorities
+func newBaseTransport(name string) (http.RoundTripper, error) {
+	proxy, _ := configuration.NamedSectionGet(name, config.Remote, config.Proxy, "")
+	caBundle, _ := configuration.NamedSectionGet(name, config.Remote, config.CaBundle, "")
+
+	if proxy == "" && caBundle == "" {
+		return http.DefaultTransport, nil
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	if proxy != "" {
+		proxyUrl, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse proxy url '%s': %v", proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	if caBundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		data, err := ioutil.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle '%s': %v", caBundle, err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in CA bundle '%s'", caBundle)
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return transport, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGithubClientUrls(t *testing.T) {
+	defer setupTestConfiguration(t, "[Remote \"ghe\"]\napi-url=https://github.example.com/api/v3\n"+
+		"[Remote \"port\"]\napi-url=http://github.example.com:8080/api/v3/\n"+
+		"[Remote \"upload\"]\napi-url=https://github.example.com/api/v3/\nupload-url=https://uploads.example.com/\n")()
+
+	tests := []struct {
+		name      string
+		apiUrl    string
+		uploadUrl string
+	}{
+		{"ghe", "https://github.example.com/api/v3/", "https://github.example.com/api/uploads/"},
+		{"port", "http://github.example.com:8080/api/v3/", "http://github.example.com:8080/api/uploads/"},
+		{"upload", "https://github.example.com/api/v3/", "https://uploads.example.com/"},
+	}
+
+	for _, test := range tests {
+		apiUrl := readApiUrl(test.name)
+		if apiUrl != test.apiUrl {
+			t.Errorf("%s: api url = %s, want %s", test.name, apiUrl, test.apiUrl)
+		}
+		if got := readUploadUrl(test.name, apiUrl); got != test.uploadUrl {
+			t.Errorf("%s: upload url = %s, want %s", test.name, got, test.uploadUrl)
+		}
+	}
+}
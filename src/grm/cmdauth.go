@@ -5,17 +5,31 @@ import (
 	"log"
 	"grm/config"
 	"fmt"
+	"strconv"
+	"io/ioutil"
+	"net/http"
+	"sort"
 )
 
 func cmdAuth(cmd *cli.Cmd) {
-	cmd.Spec = "NAME|--all [ -u=<username> ] [ -p=<password> ] [ --yes ]"
+	cmd.Spec = "NAME|--all [ --type=<auth-type> ] [ -u=<username> ] [ -p=<password> ] [ -t=<token> ] " +
+		"[ --app-id=<app-id> ] [ --installation-id=<installation-id> ] [ --private-key=<private-key-file> ] " +
+		"[ --device ] [ --client-id=<client-id> ] [ --oauth-url=<oauth-url> ] [ --yes ]"
 
 	var (
-		name     = cmd.StringArg("NAME", "", "The name of the remote definition")
-		username = cmd.StringOpt("u username", "", "The username to access Github")
-		password = cmd.StringOpt("p password", "", "The password to access Github")
-		yes      = cmd.BoolOpt("y yes", false, "Accept all questions with yes")
-		all      = cmd.BoolOpt("all", false, "Re-authorize all remote definitions")
+		name           = cmd.StringArg("NAME", "", "The name of the remote definition")
+		authType       = cmd.StringOpt("type", "", "The authorization type (basic, token, app)")
+		username       = cmd.StringOpt("u username", "", "The username to access Github")
+		password       = cmd.StringOpt("p password", "", "The password to access Github")
+		token          = cmd.StringOpt("t token", "", "The personal access token to access Github")
+		appId          = cmd.StringOpt("app-id", "", "The id of the Github App")
+		installationId = cmd.StringOpt("installation-id", "", "The installation id of the Github App")
+		privateKey     = cmd.StringOpt("private-key", "", "The PEM encoded private key file of the Github App")
+		device         = cmd.BoolOpt("device", false, "Authorize using the OAuth device flow in the browser")
+		clientId       = cmd.StringOpt("client-id", "", "The OAuth App client id used for the device flow")
+		oauthUrl       = cmd.StringOpt("oauth-url", "", "The OAuth endpoint base url, default: "+defaultOAuthUrl)
+		yes            = cmd.BoolOpt("y yes", false, "Accept all questions with yes")
+		all            = cmd.BoolOpt("all", false, "Re-authorize all remote definitions")
 	)
 
 	cmd.Action = func() {
@@ -23,6 +37,10 @@ func cmdAuth(cmd *cli.Cmd) {
 			log.Fatal("No remote name specified")
 		}
 
+		if *authType != "" && !isValidAuthType(*authType) {
+			log.Fatal(fmt.Sprintf("Unknown authorization type: %s", *authType))
+		}
+
 		readOverride := func(definition string) bool {
 			if *yes {
 				return true
@@ -33,18 +51,22 @@ func cmdAuth(cmd *cli.Cmd) {
 
 		definitions := []string{*name}
 		if *all {
-			definitions = configuration.NamedSections(config.Remote)
+			definitions = make([]string, 0)
+			for _, section := range configuration.NamedSections(config.Remote) {
+				definitions = append(definitions, config.ExtractSectionName(section))
+			}
+			sort.Strings(definitions)
 		}
 
 		for _, definition := range definitions {
-			specifier := config.ExtractSpecifier(definition)
-
 			if configuration != nil {
-				_, oku := configuration.NamedSectionGet(specifier, config.Remote, config.Username, "")
-				_, okp := configuration.NamedSectionGet(specifier, config.Remote, config.Password, "")
+				_, oku := configuration.NamedSectionGet(definition, config.Remote, config.Username, "")
+				_, okp := configuration.NamedSectionGet(definition, config.Remote, config.Password, "")
+				_, okt := configuration.NamedSectionGet(definition, config.Remote, config.Token, "")
+				_, okk := configuration.NamedSectionGet(definition, config.Remote, config.PrivateKey, "")
 
-				if oku && okp {
-					if !readOverride(specifier) {
+				if (oku && okp) || okt || okk {
+					if !readOverride(definition) {
 						// Stop execution
 						fmt.Println("Configuration not changed")
 						return
@@ -52,24 +74,180 @@ func cmdAuth(cmd *cli.Cmd) {
 				}
 			}
 
-			fmt.Println(fmt.Sprintf("Configure authorization information for remote definition: %s", specifier))
-			realUsername := *username
-			if realUsername == "" {
-				realUsername = readLine("Username:", false, "")
+			fmt.Println(fmt.Sprintf("Configure authorization information for remote definition: %s", definition))
+
+			if *device {
+				authorizeDevice(definition, *clientId, *oauthUrl)
+				continue
+			}
+
+			realAuthType := *authType
+			if realAuthType == "" {
+				switch {
+				case *username != "" || *password != "":
+					realAuthType = "basic"
+				case *token != "":
+					realAuthType = "token"
+				case *appId != "" || *installationId != "" || *privateKey != "":
+					realAuthType = "app"
+				default:
+					realAuthType = readLine("Authorization type (basic, token, app): [token]", false, "token")
+				}
+				if !isValidAuthType(realAuthType) {
+					log.Fatal(fmt.Sprintf("Unknown authorization type: %s", realAuthType))
+				}
 			}
 
-			realPassword := *password
-			if realPassword == "" {
-				realPassword = readLine("Password:", true, "")
+			switch realAuthType {
+			case "basic":
+				authorizeBasic(definition, *username, *password)
+			case "token":
+				authorizeToken(definition, *token)
+			case "app":
+				authorizeApp(definition, *appId, *installationId, *privateKey)
 			}
+		}
+	}
+}
+
+func authorizeBasic(name, username, password string) {
+	realUsername := username
+	if realUsername == "" {
+		realUsername = readLine("Username:", false, "")
+	}
 
-			encryptedPassword, salt := encrypt(realPassword, machineKey)
+	realPassword := password
+	if realPassword == "" {
+		realPassword = readLine("Password:", true, "")
+	}
+
+	encryptedPassword, salt := encrypt(realPassword, machineKey)
+
+	configuration.ApplyChanges(func(mutator config.Mutator) {
+		clearAuthorization(mutator, name)
+		mutator.NamedSectionSet(name, config.Remote, config.AuthType, "", "basic")
+		mutator.NamedSectionSet(name, config.Remote, config.Username, "", realUsername)
+		mutator.NamedSectionSet(name, config.Remote, config.Password, "", encryptedPassword)
+		mutator.NamedSectionSet(name, config.Remote, config.Salt, "", salt)
+	})
+}
+
+func authorizeToken(name, token string) {
+	realToken := token
+	if realToken == "" {
+		realToken = readLine("Personal access token:", true, "")
+	}
+
+	if realToken == "" {
+		log.Fatal("No personal access token specified")
+	}
+
+	configuration.ApplyChanges(func(mutator config.Mutator) {
+		setToken(mutator, name, realToken)
+	})
+}
+
+func setToken(mutator config.Mutator, name, token string) {
+	encryptedToken, salt := encrypt(token, machineKey)
+
+	clearAuthorization(mutator, name)
+	mutator.NamedSectionSet(name, config.Remote, config.AuthType, "", "token")
+	mutator.NamedSectionSet(name, config.Remote, config.Token, "", encryptedToken)
+	mutator.NamedSectionSet(name, config.Remote, config.Salt, "", salt)
+}
 
-			configuration.ApplyChanges(func(mutator config.Mutator) {
-				mutator.NamedSectionSet(specifier, config.Remote, config.Username, "", realUsername)
-				mutator.NamedSectionSet(specifier, config.Remote, config.Password, "", encryptedPassword)
-				mutator.NamedSectionSet(specifier, config.Remote, config.Salt, "", salt)
-			})
+func authorizeDevice(name, clientId, oauthUrl string) {
+	realClientId := clientId
+	if realClientId == "" {
+		if c, ok := configuration.NamedSectionGet(name, config.Remote, config.OAuthClientId, ""); ok {
+			realClientId = c
 		}
 	}
+	if realClientId == "" {
+		realClientId = readLine("OAuth App client id:", false, "")
+	}
+	if realClientId == "" {
+		log.Fatal("No OAuth App client id specified")
+	}
+
+	realOAuthUrl := oauthUrl
+	if realOAuthUrl == "" {
+		realOAuthUrl = defaultOAuthUrl
+		if u, ok := configuration.NamedSectionGet(name, config.Remote, config.OAuthUrl, ""); ok && u != "" {
+			realOAuthUrl = u
+		}
+	}
+
+	transport, err := newBaseTransport(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := &http.Client{Transport: transport}
+	token, err := runDeviceFlow(client, realOAuthUrl, realClientId, defaultOAuthScope, func(code *deviceCode) {
+		fmt.Println(fmt.Sprintf("Please open %s and enter the code: %s", code.VerificationUri, code.UserCode))
+		fmt.Println("Waiting for authorization...")
+	})
+	if err != nil {
+		log.Fatal("Device authorization failed: ", err)
+	}
+
+	configuration.ApplyChanges(func(mutator config.Mutator) {
+		setToken(mutator, name, token)
+		mutator.NamedSectionSet(name, config.Remote, config.OAuthClientId, "", realClientId)
+		if realOAuthUrl != defaultOAuthUrl {
+			mutator.NamedSectionSet(name, config.Remote, config.OAuthUrl, "", realOAuthUrl)
+		}
+	})
+}
+
+func authorizeApp(name, appId, installationId, privateKeyFile string) {
+	realAppId := appId
+	if realAppId == "" {
+		realAppId = readLine("Github App id:", false, "")
+	}
+	if _, err := strconv.ParseInt(realAppId, 10, 64); err != nil {
+		log.Fatal(fmt.Sprintf("Illegal Github App id '%s': ", realAppId), err)
+	}
+
+	realInstallationId := installationId
+	if realInstallationId == "" {
+		realInstallationId = readLine("Github App installation id:", false, "")
+	}
+	if _, err := strconv.ParseInt(realInstallationId, 10, 64); err != nil {
+		log.Fatal(fmt.Sprintf("Illegal Github App installation id '%s': ", realInstallationId), err)
+	}
+
+	realPrivateKeyFile := privateKeyFile
+	if realPrivateKeyFile == "" {
+		realPrivateKeyFile = readLine("Github App private key file (PEM):", false, "")
+	}
+
+	privateKey, err := ioutil.ReadFile(realPrivateKeyFile)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("Could not read private key file '%s': ", realPrivateKeyFile), err)
+	}
+	if _, err := parsePrivateKey(privateKey); err != nil {
+		log.Fatal(fmt.Sprintf("Could not parse private key file '%s': ", realPrivateKeyFile), err)
+	}
+
+	encryptedPrivateKey, salt := encrypt(string(privateKey), machineKey)
+
+	configuration.ApplyChanges(func(mutator config.Mutator) {
+		clearAuthorization(mutator, name)
+		mutator.NamedSectionSet(name, config.Remote, config.AuthType, "", "app")
+		mutator.NamedSectionSet(name, config.Remote, config.AppId, "", realAppId)
+		mutator.NamedSectionSet(name, config.Remote, config.InstallationId, "", realInstallationId)
+		mutator.NamedSectionSet(name, config.Remote, config.PrivateKey, "", encryptedPrivateKey)
+		mutator.NamedSectionSet(name, config.Remote, config.Salt, "", salt)
+	})
+}
+
+// Removes all previously stored credentials, independent of their authorization type
+func clearAuthorization(mutator config.Mutator, name string) {
+	mutator.NamedSectionDelete(name, config.Remote, config.Password, "")
+	mutator.NamedSectionDelete(name, config.Remote, config.Token, "")
+	mutator.NamedSectionDelete(name, config.Remote, config.AppId, "")
+	mutator.NamedSectionDelete(name, config.Remote, config.InstallationId, "")
+	mutator.NamedSectionDelete(name, config.Remote, config.PrivateKey, "")
+	mutator.NamedSectionDelete(name, config.Remote, config.Salt, "")
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"grm/config"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestAuthorizeDeviceStoresTokenInDefinition(t *testing.T) {
+	defer setupTestConfiguration(t, "[Remote \"foo\"]\nuser=noctarius\n")()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		switch request.URL.Path {
+		case "/login/device/code":
+			writer.Write([]byte(`{"device_code":"dc","user_code":"ABCD-1234","verification_uri":"https://example.com/device","interval":1}`))
+		case "/login/oauth/access_token":
+			writer.Write([]byte(`{"access_token":"device-token"}`))
+		default:
+			http.NotFound(writer, request)
+		}
+	}))
+	defer server.Close()
+
+	authorizeDevice("foo", "client", server.URL)
+
+	// The token is persisted, so the configuration is read back like on the next run
+	configuration = config.NewConfiguration(*homeDir)
+
+	token, ok := configuration.NamedSectionGet("foo", config.Remote, config.Token, "")
+	if !ok {
+		t.Fatal("no token stored for definition foo")
+	}
+	salt, _ := configuration.NamedSectionGet("foo", config.Remote, config.Salt, "")
+	if decrypted, err := decrypt(token, salt, machineKey); err != nil || decrypted != "device-token" {
+		t.Errorf("stored token = %q, want device-token", decrypted)
+	}
+	if authType, _ := configuration.NamedSectionGet("foo", config.Remote, config.AuthType, ""); authType != "token" {
+		t.Errorf("auth-type = %q, want token", authType)
+	}
+	if clientId, _ := configuration.NamedSectionGet("foo", config.Remote, config.OAuthClientId, ""); clientId != "client" {
+		t.Errorf("oauth-client-id = %q, want client", clientId)
+	}
+	if _, ok := configuration.NamedSectionGet("", config.Remote, config.Token, ""); ok {
+		t.Error("token stored for the empty definition name")
+	}
+}
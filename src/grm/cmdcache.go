@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/jawher/mow.cli"
+	"log"
+	"fmt"
+	"grm/cache"
+)
+
+func cmdCache(cmd *cli.Cmd) {
+	cmd.Command("stats", "Prints statistics of the cached Github API responses", cmdCacheStats)
+	cmd.Command("clear", "Removes the cached Github API responses", cmdCacheClear)
+}
+
+func cmdCacheStats(cmd *cli.Cmd) {
+	cmd.Spec = "[ NAME ]"
+
+	var (
+		name = cmd.StringArg("NAME", "", "The name of the remote definition, default: all definitions")
+	)
+
+	cmd.Action = func() {
+		stats, err := cache.ReadStats(*homeDir, *name)
+		if err != nil {
+			log.Fatal("Could not read cache: ", err)
+		}
+
+		if stats.Entries == 0 {
+			fmt.Println("Cache is empty")
+			return
+		}
+
+		fmt.Println(fmt.Sprintf("Entries: %d (%d permanent)", stats.Entries, stats.Permanent))
+		fmt.Println(fmt.Sprintf("Size: %.1f KiB", float64(stats.Size)/1024))
+		fmt.Println(fmt.Sprintf("Oldest entry: %s", stats.Oldest.Format("2006-01-02 15:04:05")))
+	}
+}
+
+func cmdCacheClear(cmd *cli.Cmd) {
+	cmd.Spec = "[ NAME ]"
+
+	var (
+		name = cmd.StringArg("NAME", "", "The name of the remote definition, default: all definitions")
+	)
+
+	cmd.Action = func() {
+		if err := cache.Clear(*homeDir, *name); err != nil {
+			log.Fatal("Could not clear cache: ", err)
+		}
+		fmt.Println("Cache cleared")
+	}
+}
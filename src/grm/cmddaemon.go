@@ -0,0 +1,467 @@
+package main
+
+import (
+	"github.com/jawher/mow.cli"
+	"github.com/vbauerster/mpb"
+	"grm/config"
+	"grm/state"
+	"log"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"sync"
+	"time"
+	"net/http"
+	"encoding/json"
+	"io/ioutil"
+	"context"
+	"sort"
+	"github.com/google/go-github/github"
+)
+
+// Definitions without schedule property are polled hourly
+const defaultSchedule = "@hourly"
+
+// The daemon keeps its own state, releases it notified about are still new to the report command
+const daemonStateNamespace = "daemon"
+
+// Polls the remote definitions on their schedule and notifies about new releases
+type daemon struct {
+	names    []string
+	lookback string
+	started  time.Time
+	verifier *downloadVerifier
+
+	// Polls read the configuration, reloads replace it
+	config sync.RWMutex
+
+	mutex       sync.Mutex
+	definitions map[string]*daemonDefinition
+	polls       sync.WaitGroup
+	reload      chan struct{}
+}
+
+type daemonDefinition struct {
+	name     string
+	client   *github.Client
+	schedule *schedule
+	sinks    []notificationSink
+	status   *daemonStatus
+
+	// Until a poll without errors recorded the existing releases, found releases are not notified.
+	// The baseline is persisted in the daemon state, so restarts of the daemon keep it.
+	baseline bool
+}
+
+// Status of a definition as served by the status endpoint
+type daemonStatus struct {
+	Name         string     `json:"name"`
+	Schedule     string     `json:"schedule"`
+	Running      bool       `json:"running"`
+	NextPoll     time.Time  `json:"nextPoll"`
+	LastPoll     *time.Time `json:"lastPoll,omitempty"`
+	LastSuccess  *time.Time `json:"lastSuccess,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+	Repositories int        `json:"repositories"`
+	Notified     int        `json:"notified"`
+}
+
+type daemonStatusDocument struct {
+	Started     time.Time       `json:"started"`
+	Definitions []*daemonStatus `json:"definitions"`
+}
+
+func cmdDaemon(cmd *cli.Cmd) {
+	cmd.Spec = "[ NAME... ] [ --lookback=<lookback> ] [ --status-address=<status-address> ]"
+
+	var (
+		names         = cmd.StringsArg("NAME", nil, "The names of the remote definitions, default: all definitions")
+		lookback      = cmd.StringOpt("lookback", "30d", "How far back to look for new releases on every poll, e.g. 2w")
+		statusAddress = cmd.StringOpt("status-address", "", "The address to serve the status endpoint on, e.g. :8080")
+	)
+
+	cmd.Action = func() {
+		if _, _, err := parseDateExpression(*lookback, time.Now(), time.UTC); err != nil {
+			log.Fatal(fmt.Sprintf("Could not parse lookback '%s': ", *lookback), err)
+		}
+
+		d := &daemon{
+			names:       *names,
+			lookback:    *lookback,
+			started:     time.Now(),
+			verifier:    newDownloadVerifier(*homeDir, true),
+			definitions: make(map[string]*daemonDefinition),
+			reload:      make(chan struct{}, 1),
+		}
+
+		definitions, err := d.readDefinitions()
+		if err != nil {
+			log.Fatal(err)
+		}
+		d.definitions = definitions
+
+		var server *http.Server
+		if *statusAddress != "" {
+			server = &http.Server{Addr: *statusAddress, Handler: http.HandlerFunc(d.serveStatus)}
+			go func() {
+				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Fatal("Could not serve status endpoint: ", err)
+				}
+			}()
+			log.Printf("Serving status on %s/status", *statusAddress)
+		}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+
+		stop := make(chan struct{})
+		go func() {
+			for s := range signals {
+				if s == syscall.SIGHUP {
+					d.reloadConfiguration()
+					continue
+				}
+				log.Printf("Received %s, shutting down", s)
+				close(stop)
+				return
+			}
+		}()
+
+		d.run(stop)
+
+		// Running polls finish, so that all sent notifications are marked as seen
+		d.polls.Wait()
+		if server != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			server.Shutdown(ctx)
+			cancel()
+		}
+		log.Printf("Stopped")
+	}
+}
+
+// Reads the schedule, client and notification sinks of all polled definitions. Invalid settings
+// are reported instead of exiting, so that a reload can keep the previous definitions.
+func (d *daemon) readDefinitions() (map[string]*daemonDefinition, error) {
+	names := d.names
+	if len(names) == 0 {
+		for _, section := range configuration.NamedSections(config.Remote) {
+			names = append(names, config.ExtractSectionName(section))
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no remote definitions configured")
+	}
+
+	now := time.Now()
+	definitions := make(map[string]*daemonDefinition, len(names))
+	for _, name := range names {
+		expression := defaultSchedule
+		if s, ok := configuration.NamedSectionGet(name, config.Remote, config.Schedule, ""); ok && s != "" {
+			expression = s
+		}
+
+		location, err := readLocation(name, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone of %s: %v", name, err)
+		}
+		sched, err := parseSchedule(expression, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule of %s: %v", name, err)
+		}
+		sinks, err := readNotificationSinks(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid notification sinks of %s: %v", name, err)
+		}
+		client, err := newGithubClient(name, true)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client settings of %s: %v", name, err)
+		}
+		store, err := state.NewStore(*homeDir, daemonStateNamespace, name)
+		if err != nil {
+			return nil, err
+		}
+
+		definitions[name] = &daemonDefinition{
+			name:     name,
+			client:   client,
+			schedule: sched,
+			sinks:    sinks,
+			baseline: store.Initialized(),
+			status:   &daemonStatus{Name: name, Schedule: expression, NextPoll: sched.next(now)},
+		}
+	}
+	return definitions, nil
+}
+
+// Waits for the next scheduled poll of any definition until stopped
+func (d *daemon) run(stop <-chan struct{}) {
+	for {
+		d.mutex.Lock()
+		next := time.Time{}
+		for _, definition := range d.definitions {
+			if next.IsZero() || definition.status.NextPoll.Before(next) {
+				next = definition.status.NextPoll
+			}
+		}
+		d.mutex.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-d.reload:
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		d.mutex.Lock()
+		for _, definition := range d.definitions {
+			if definition.status.NextPoll.After(now) {
+				continue
+			}
+			definition.status.NextPoll = definition.schedule.next(now)
+
+			// A poll taking longer than the schedule interval skips the overlapping polls
+			if definition.status.Running {
+				log.Printf("Skipping poll of %s, previous poll still running", definition.name)
+				continue
+			}
+			definition.status.Running = true
+			d.polls.Add(1)
+			go d.poll(definition)
+		}
+		d.mutex.Unlock()
+	}
+}
+
+// Scans a definition for releases not seen before and sends them to the notification sinks.
+// Only successfully notified releases are marked as seen, others are retried on the next poll.
+// The first polls of a definition without any seen releases only record the existing releases.
+func (d *daemon) poll(definition *daemonDefinition) {
+	defer d.polls.Done()
+
+	d.config.RLock()
+	notified, repositories, err := d.scan(definition)
+	d.config.RUnlock()
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now()
+	definition.status.Running = false
+	definition.status.LastPoll = &now
+	definition.status.Repositories = repositories
+	definition.status.Notified += notified
+	if err != nil {
+		definition.status.LastError = err.Error()
+		log.Printf("Poll of %s failed: %v", definition.name, err)
+		return
+	}
+	definition.status.LastError = ""
+	definition.status.LastSuccess = &now
+}
+
+func (d *daemon) scan(definition *daemonDefinition) (int, int, error) {
+	name := definition.name
+	now := time.Now()
+
+	since, _, err := parseDateExpression(d.lookback, now, time.UTC)
+	if err != nil {
+		return 0, 0, err
+	}
+	dates := dateRange{since: since}
+
+	client := definition.client
+	repos, err := readDefinitionRepositories(name, false, "", client)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	resolvers, err := newDownloadResolvers(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	fetcher, err := newRepositoryFetcher(name, repos, client)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	scan := &definitionScan{name: name, client: client, repos: repos, resolvers: resolvers}
+	scanErrs := newScanErrors(0)
+	progress := mpb.New(mpb.WithOutput(ioutil.Discard))
+	scan.repositories = selectRepositories(progress, repos, name, dates, &scanOptions{}, scanErrs, fetcher, scan.resolvers)
+	progress.Wait()
+
+	for _, e := range scanErrs.errors {
+		log.Printf("Error scanning %s: %v", e.repository, e.err)
+	}
+
+	count, err := d.processReleases(definition, scan, dates, scanErrs)
+	if err != nil {
+		return count, len(repos), err
+	}
+
+	if len(scanErrs.errors) > 0 {
+		return count, len(repos), fmt.Errorf("%d errors while scanning %d repositories", len(scanErrs.errors), len(repos))
+	}
+	return count, len(repos), nil
+}
+
+// Records the releases found by the first polls as baseline, later polls notify about the releases
+// not seen before. Returns the number of notified releases.
+func (d *daemon) processReleases(definition *daemonDefinition, scan *definitionScan, dates dateRange, scanErrs *scanErrors) (int, error) {
+	name := definition.name
+	store, err := state.NewStore(*homeDir, daemonStateNamespace, name)
+	if err != nil {
+		return 0, err
+	}
+	scan.store = store
+
+	if definition.baseline {
+		return d.notifyReleases(definition, scan, dates, scanErrs), nil
+	}
+
+	log.Printf("First poll of %s, recording existing releases without notification", name)
+	if err := markReleasesSeen(scan.repositories, scan.store); err != nil {
+		return 0, err
+	}
+
+	// Releases of repositories failing this poll are not recorded yet and must not be notified
+	// as new later, so the baseline is only complete after a poll without errors. It is stored
+	// separately from the marked releases, as a definition may have no releases at all yet.
+	if len(scanErrs.errors) == 0 {
+		if err := scan.store.Initialize(); err != nil {
+			return 0, err
+		}
+		definition.baseline = true
+	}
+	return 0, nil
+}
+
+// Notifies about the releases not seen before and marks the successfully notified releases as seen
+func (d *daemon) notifyReleases(definition *daemonDefinition, scan *definitionScan, dates dateRange, scanErrs *scanErrors) int {
+	name := definition.name
+	scan.repositories = filterSeenReleases(scan.repositories, scan.store)
+	verifyReleaseDownloads([]*definitionScan{scan}, d.verifier, false, scanErrs)
+	if err := d.verifier.save(); err != nil {
+		log.Printf("Could not cache download verifications: %v", err)
+	}
+
+	notified := make([]*repository, 0)
+	count := 0
+	for _, rep := range scan.repositories {
+		document := buildReportDocument([]string{name}, dates, []*repository{rep})
+		if len(document.Repositories) == 0 {
+			continue
+		}
+
+		releases := reportedReleases(rep)
+		delivered := make([]*release, 0, len(releases))
+		for i, reportRel := range document.Repositories[0].Releases {
+			notification := &releaseNotification{
+				Definition:    name,
+				Repository:    repositoryFullName(rep),
+				RepositoryUrl: rep.url,
+				Release:       reportRel,
+			}
+			if d.notify(definition, notification) {
+				delivered = append(delivered, releases[i])
+				count++
+			}
+		}
+
+		if len(delivered) > 0 {
+			notified = append(notified, &repository{name: rep.name, owner: rep.owner, url: rep.url, definition: name, releases: delivered})
+		}
+	}
+
+	if len(notified) > 0 {
+		if err := markReleasesSeen(notified, scan.store); err != nil {
+			log.Printf("Could not mark notified releases of %s as seen: %v", name, err)
+		}
+	}
+	return count
+}
+
+// Sends the notification to all sinks, tells if every sink succeeded
+func (d *daemon) notify(definition *daemonDefinition, notification *releaseNotification) bool {
+	delivered := true
+	for _, sink := range definition.sinks {
+		if err := sink.notify(notification); err != nil {
+			log.Printf("Could not notify %s about %s %s: %v", sink, notification.Repository, notification.Release.Tag, err)
+			delivered = false
+		}
+	}
+	return delivered
+}
+
+// Reloads the configuration, an invalid configuration is rejected and the previous one kept
+func (d *daemon) reloadConfiguration() {
+	log.Printf("Reloading configuration")
+
+	// Waits for running polls to finish reading the configuration
+	d.config.Lock()
+	previous := configuration
+	configuration = config.NewConfiguration(*homeDir)
+	definitions, err := d.readDefinitions()
+	if err != nil {
+		configuration = previous
+		d.config.Unlock()
+		log.Printf("Could not reload configuration, keeping the previous one: %v", err)
+		return
+	}
+	d.config.Unlock()
+
+	d.mutex.Lock()
+	for name, definition := range definitions {
+		if existing, ok := d.definitions[name]; ok {
+			// Statistics survive reloads, the next poll follows the new schedule
+			nextPoll := definition.status.NextPoll
+			definition.baseline = existing.baseline
+			definition.status = existing.status
+			definition.status.Schedule = definition.schedule.String()
+			definition.status.NextPoll = nextPoll
+		}
+	}
+	d.definitions = definitions
+	d.mutex.Unlock()
+
+	select {
+	case d.reload <- struct{}{}:
+	default:
+	}
+}
+
+func (d *daemon) serveStatus(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/status" {
+		http.NotFound(writer, request)
+		return
+	}
+
+	d.mutex.Lock()
+	document := &daemonStatusDocument{Started: d.started, Definitions: make([]*daemonStatus, 0, len(d.definitions))}
+	for _, name := range sortedDefinitionNames(d.definitions) {
+		status := *d.definitions[name].status
+		document.Definitions = append(document.Definitions, &status)
+	}
+	d.mutex.Unlock()
+
+	writer.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(document)
+}
+
+func sortedDefinitionNames(definitions map[string]*daemonDefinition) []string {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"testing"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"grm/state"
+	"strings"
+	"grm/config"
+)
+
+// Builds a definition authorized by an encrypted token, settings are appended as given
+func testDaemonDefinition(name, settings string) string {
+	token, salt := encrypt("token", machineKey)
+	return fmt.Sprintf("[Remote \"%s\"]\nauth-type=token\ntoken=%s\nsalt=%s\n%s", name, token, salt, settings)
+}
+
+func TestDaemonReadDefinitions(t *testing.T) {
+	defer setupTestConfiguration(t, "")()
+
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"valid", testDaemonDefinition("foo", "schedule=*/30 * * * *\ntimezone=Europe/Berlin\n"), ""},
+		{"never matching schedule", testDaemonDefinition("foo", "schedule=0 0 31 2 *\n"), "invalid schedule of foo"},
+		{"unknown timezone", testDaemonDefinition("foo", "timezone=Mars/Olympus\n"), "invalid timezone of foo"},
+		{"invalid proxy", testDaemonDefinition("foo", "proxy=http://[::1\n"), "could not parse proxy url"},
+		{"missing credentials", "[Remote \"foo\"]\nauth-type=token\n", "invalid client settings of foo"},
+	}
+
+	for _, test := range tests {
+		writeTestConfiguration(t, test.content)
+
+		d := &daemon{names: []string{"foo"}}
+		definitions, err := d.readDefinitions()
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			} else if definitions["foo"].client == nil || definitions["foo"].status.NextPoll.IsZero() {
+				t.Errorf("%s: incomplete definition %+v", test.name, definitions["foo"])
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error = %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+// Replaces the configuration file of the test and reads it
+func writeTestConfiguration(t *testing.T, content string) {
+	configPath := filepath.Join(*homeDir, "github-release-monitor", "config")
+	if err := ioutil.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	configuration = config.NewConfiguration(*homeDir)
+}
+
+func TestDaemonReloadKeepsDefinitionsOnError(t *testing.T) {
+	defer setupTestConfiguration(t, "")()
+	writeTestConfiguration(t, testDaemonDefinition("foo", "schedule=@daily\n"))
+	configPath := filepath.Join(*homeDir, "github-release-monitor", "config")
+
+	d := &daemon{names: []string{"foo"}, reload: make(chan struct{}, 1)}
+	definitions, err := d.readDefinitions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.definitions = definitions
+	previous := configuration
+
+	if err := ioutil.WriteFile(configPath, []byte(testDaemonDefinition("foo", "timezone=Mars/Olympus\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	d.reloadConfiguration()
+	if configuration != previous || d.definitions["foo"] != definitions["foo"] {
+		t.Error("failed reload replaced the configuration or definitions")
+	}
+
+	if err := ioutil.WriteFile(configPath, []byte(testDaemonDefinition("foo", "schedule=@hourly\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	d.reloadConfiguration()
+	if schedule := d.definitions["foo"].status.Schedule; schedule != "@hourly" {
+		t.Errorf("schedule after reload = %s, want @hourly", schedule)
+	}
+}
+
+func TestDaemonStateIsSeparate(t *testing.T) {
+	defer setupTestConfiguration(t, "")()
+
+	daemonStore, err := state.NewStore(*homeDir, daemonStateNamespace, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := daemonStore.Mark([]*state.Release{{Repository: "noctarius/example", Tag: "v1.0.0"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if reportStore, _ := state.NewStore(*homeDir, "", "foo"); reportStore.Seen("noctarius/example", "v1.0.0") {
+		t.Error("release notified by the daemon is seen by the report")
+	}
+	if daemonStore, _ := state.NewStore(*homeDir, daemonStateNamespace, "foo"); !daemonStore.Seen("noctarius/example", "v1.0.0") {
+		t.Error("daemon state was not persisted")
+	}
+}
+
+// Records the tags of delivered notifications, notifications of failing tags are rejected
+type testSink struct {
+	failing  map[string]bool
+	notified []string
+}
+
+func (s *testSink) notify(notification *releaseNotification) error {
+	if s.failing[notification.Release.Tag] {
+		return fmt.Errorf("failed to deliver %s", notification.Release.Tag)
+	}
+	s.notified = append(s.notified, notification.Release.Tag)
+	return nil
+}
+
+func (s *testSink) String() string {
+	return "test"
+}
+
+func testDaemonScan(tags ...string) *definitionScan {
+	releases := make([]*release, 0, len(tags))
+	for _, tag := range tags {
+		releases = append(releases, &release{name: tag, tag: tag, created: testNow,
+			notesUrl: "https://github.com/noctarius/example/releases/tag/" + tag})
+	}
+	rep := &repository{owner: "noctarius", name: "example", definition: "foo", releases: releases}
+	return &definitionScan{name: "foo", repositories: []*repository{rep}}
+}
+
+func TestDaemonPollNotifiesAndMarks(t *testing.T) {
+	defer setupTestConfiguration(t, "")()
+	writeTestConfiguration(t, testDaemonDefinition("foo", ""))
+
+	sink := &testSink{failing: make(map[string]bool)}
+	d := &daemon{names: []string{"foo"}, verifier: newDownloadVerifier(*homeDir, false)}
+	definition := &daemonDefinition{name: "foo", sinks: []notificationSink{sink}}
+
+	tests := []struct {
+		name     string
+		tags     []string
+		failing  string
+		notified []string
+	}{
+		// The definition has no releases yet, the baseline must be recorded nonetheless
+		{"first poll without releases", nil, "", nil},
+		{"new release", []string{"v1.0.0"}, "", []string{"v1.0.0"}},
+		{"release already notified", []string{"v1.0.0"}, "", nil},
+		{"failed notification", []string{"v1.0.0", "v1.1.0"}, "v1.1.0", nil},
+		{"failed notification is retried", []string{"v1.0.0", "v1.1.0"}, "", []string{"v1.1.0"}},
+	}
+
+	for i, test := range tests {
+		sink.notified = nil
+		sink.failing = map[string]bool{test.failing: true}
+
+		count, err := d.processReleases(definition, testDaemonScan(test.tags...), dateRange{}, newScanErrors(0))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if count != len(test.notified) || strings.Join(sink.notified, ",") != strings.Join(test.notified, ",") {
+			t.Errorf("%s: notified %d %v, want %v", test.name, count, sink.notified, test.notified)
+		}
+
+		// A restarted daemon continues with the persisted baseline
+		if i == 0 {
+			definitions, err := d.readDefinitions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !definitions["foo"].baseline {
+				t.Errorf("%s: baseline not persisted", test.name)
+			}
+		}
+	}
+}
+
+func TestDaemonPollWithErrorsHasNoBaseline(t *testing.T) {
+	defer setupTestConfiguration(t, "")()
+
+	sink := &testSink{}
+	d := &daemon{names: []string{"foo"}, verifier: newDownloadVerifier(*homeDir, false)}
+	definition := &daemonDefinition{name: "foo", sinks: []notificationSink{sink}}
+
+	scanErrs := newScanErrors(0)
+	scanErrs.report("noctarius/other", []error{fmt.Errorf("failure")})
+	if _, err := d.processReleases(definition, testDaemonScan("v1.0.0"), dateRange{}, scanErrs); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := state.NewStore(*homeDir, daemonStateNamespace, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if definition.baseline || store.Initialized() {
+		t.Error("baseline recorded by a poll with errors")
+	}
+	if !store.Seen("noctarius/example", "v1.0.0") || len(sink.notified) > 0 {
+		t.Error("release of a scanned repository was notified instead of recorded")
+	}
+}
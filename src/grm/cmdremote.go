@@ -18,7 +18,7 @@ func cmdRemoteAdd(cmd *cli.Cmd) {
 
 	var (
 		name              = cmd.StringArg("NAME", "", "The name of the remote definition")
-		user              = cmd.StringArg("USER", "", "The remote user to be registered")
+		user              = cmd.StringArg("USER", "", "The remote users or organizations to be registered, comma separated")
 		private           = cmd.BoolOpt("p private", false, "Will analyze private repositories, default: false")
 		releasePattern    = cmd.StringOpt("release-pattern", "", "The default pattern to match tag names")
 		repositoryPattern = cmd.StringOpt("repository-pattern", "", "The default pattern to match repository names")
@@ -60,9 +60,12 @@ func cmdRemoteAdd(cmd *cli.Cmd) {
 			fmt.Println("The basic download url is the template to generate download urls for releases.")
 			fmt.Println("The template can contain template placeholders to be filled automatically.")
 			fmt.Println("Current template placeholders are:")
-			fmt.Println(" * {account}: The Github remote username (from the remote definition)")
+			fmt.Println(" * {account}, {owner}: The owner of the current repository")
 			fmt.Println(" * {repository}: The name of the current repository")
 			fmt.Println(" * {version}: The current release version")
+			fmt.Println(" * {tag}, {milestone}, {date}: The tag, milestone title and date (YYYY-MM-DD) of the release")
+			fmt.Println(" * {major}, {minor}, {patch}: The version numbers of the release")
+			fmt.Println("Transforms are appended to placeholders, e.g. {version|strip-v} or {\"com.example\"|path}")
 			realDownloadUrl = readLine("Basic download url: [http://download.example.com/{account}/{repository}/{version}]",
 				false, "http://download.example.com/{account}/{repository}/{version}")
 		}
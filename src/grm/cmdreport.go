@@ -9,106 +9,414 @@ import (
 	"strconv"
 	"regexp"
 	"time"
-	"github.com/araddon/dateparse"
 	"sync"
 	"github.com/vbauerster/mpb"
 	"github.com/vbauerster/mpb/decor"
 	"strings"
 	"net/http"
 	"grm/config"
+	"os"
+	"sort"
+	"grm/state"
+	"grm/version"
+)
+
+const (
+	// Some repositories could not be scanned, the report is incomplete
+	exitPartialFailure = 2
+	// No repository could be scanned
+	exitCompleteFailure = 3
 )
 
 func cmdReport(cmd *cli.Cmd) {
-	cmd.Spec = "NAME  [ -p=<private_repos> ] [ --repository-pattern=<repository-pattern> ] [ --since=<since> ]"
+	cmd.Spec = "NAME...|--all [ -p=<private_repos> ] [ --repository-pattern=<repository-pattern> ] [ --since=<since> ] [ --until=<until> ] [ --period=<period> ] [ --timezone=<timezone> ] [ --format=<format> ] [ --template=<template> ] [ --order=<order> ] [ --changelog ] [ --changelog-limit=<changelog-limit> ] [ --contributors ] [ --checksums ] [ --new ] [ --mark-seen ] [ --min-bump=<min-bump> ] [ --exclude-prerelease ] [ --latest-only ] [ --verify-downloads=<verify-downloads> ] [ --no-cache ] [ --fail-fast | --max-errors=<max-errors> ]"
 
 	var (
-		name              = cmd.StringArg("NAME", "", "The name of the remote definition")
+		names             = cmd.StringsArg("NAME", nil, "The names of the remote definitions")
+		all               = cmd.BoolOpt("all", false, "Report the releases of all remote definitions")
 		private           = cmd.BoolOpt("p private", false, "Analyze private repositories, default: false")
 		repositoryPattern = cmd.StringOpt("repository-pattern", "", "A pattern to match repository names")
-		since             = cmd.StringOpt("since", "", "Date of search begin in ISO format YYYY-MM-DD")
+		since             = cmd.StringOpt("since", "", "Date of search begin in ISO format YYYY-MM-DD or relative, e.g. 2w or last-monday")
+		until             = cmd.StringOpt("until", "", "Date of search end in ISO format YYYY-MM-DD or relative, e.g. yesterday")
+		period            = cmd.StringOpt("period", "", "A period to search, e.g. last-month or 2018-Q2, instead of since and until")
+		timezone          = cmd.StringOpt("timezone", "", "The timezone to interpret dates in, e.g. Europe/Berlin, default: UTC")
+		format            = cmd.StringOpt("format", "", "The output format of the report (text, json, template), default: text")
+		templateFile      = cmd.StringOpt("template", "", "A text/template (or html/template for .html files) to render the report")
+		order             = cmd.StringOpt("order", "definition", "The order of the text report (definition, chronological)")
+		changelog         = cmd.BoolOpt("changelog", false, "Lists the closed issues and pull requests of every release milestone")
+		changelogLimit    = cmd.IntOpt("changelog-limit", 10, "The maximum number of changelog entries per release, 0 for unlimited")
+		contributors      = cmd.BoolOpt("contributors", false, "Lists the contributors of every release")
+		checksums         = cmd.BoolOpt("checksums", false, "Matches the checksum files published next to release assets")
+		onlyNew           = cmd.BoolOpt("new", false, "Only report releases not yet marked as seen")
+		markSeen          = cmd.BoolOpt("mark-seen", false, "Marks all reported releases as seen")
+		minBump           = cmd.StringOpt("min-bump", "patch", "Only report releases with at least the given version bump (major, minor, patch)")
+		excludePrerelease = cmd.BoolOpt("exclude-prerelease", false, "Excludes pre-releases from the report")
+		latestOnly        = cmd.BoolOpt("latest-only", false, "Only report the latest release of every repository")
+		verifyDownloads   = cmd.StringOpt("verify-downloads", "warn", "Verifies download urls (off, warn, require)")
+		noCache           = cmd.BoolOpt("no-cache", false, "Bypasses the on-disk cache of Github API responses")
+		failFast          = cmd.BoolOpt("fail-fast", false, "Stop scanning on the first error")
+		maxErrors         = cmd.IntOpt("max-errors", 0, "Stop scanning after the given number of errors, default: unlimited")
 	)
 
 	cmd.Action = func() {
-		if *name == "" {
+		definitions := *names
+		if *all {
+			definitions = make([]string, 0)
+			for _, section := range configuration.NamedSections(config.Remote) {
+				definitions = append(definitions, config.ExtractSectionName(section))
+			}
+			sort.Strings(definitions)
+		}
+		if len(definitions) == 0 {
 			log.Fatal("No remote name specified")
 		}
 
-		username, ok := configuration.NamedSectionGet(*name, config.Remote, config.Username, "")
-		if !ok {
-			log.Fatal(fmt.Sprintf("Could not retrieve username from config, please run 'grm auth %s'", *name))
+		// Properties of the report as a whole are taken from the first definition
+		name := definitions[0]
+		if err := checkMergedDefinitions(definitions, *templateFile, *timezone); err != nil {
+			log.Fatal(err)
 		}
-		pass, ok := configuration.NamedSectionGet(*name, config.Remote, config.Password, "")
-		if !ok {
-			log.Fatal(fmt.Sprintf("Could not retrieve password from config, please run 'grm auth %s'", *name))
+
+		reportTemplate := *templateFile
+		if reportTemplate == "" {
+			if t, ok := configuration.NamedSectionGet(name, config.Remote, config.ReportTemplate, ""); ok {
+				reportTemplate = t
+			}
 		}
 
-		salt, ok := configuration.NamedSectionGet(*name, config.Remote, config.Salt, "")
-		if !ok {
-			log.Fatal(fmt.Sprintf("Could not retrieve salt from config, please run 'grm auth %s'", *name))
+		reportFormat := *format
+		if reportFormat == "" {
+			reportFormat = "text"
+			if reportTemplate != "" {
+				reportFormat = "template"
+			}
 		}
 
-		basicAuth := github.BasicAuthTransport{
-			Username: username,
-			Password: decrypt(pass, salt, machineKey),
+		if !isValidReportFormat(reportFormat) {
+			log.Fatal(fmt.Sprintf("Unknown report format: %s", reportFormat))
+		}
+		if !isValidReportOrder(*order) {
+			log.Fatal(fmt.Sprintf("Unknown report order: %s", *order))
+		}
+		if !isValidVerifyMode(*verifyDownloads) {
+			log.Fatal(fmt.Sprintf("Unknown download verification mode: %s", *verifyDownloads))
 		}
 
-		client := github.NewClient(basicAuth.Client())
+		var tmpl reportTemplateRenderer = nil
+		if reportFormat == "template" {
+			if reportTemplate == "" {
+				log.Fatal("No report template specified, use --template or the report-template property")
+			}
+			tmpl = loadReportTemplate(reportTemplate)
+		}
 
-		remoteAccount := username
-		showPrivate := *private
-		repositoryPattern := *repositoryPattern
-		if r, ok := configuration.NamedSectionGet(*name, config.Remote, config.RepositoryPattern, ""); ok {
-			repositoryPattern = r
+		minimumBump, err := version.ParseBump(*minBump)
+		if err != nil {
+			log.Fatal(err)
 		}
 
-		date := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
-		if *since != "" {
-			d, err := dateparse.ParseIn(*since, time.UTC)
-			if err != nil {
-				log.Fatal("Could not parse since data", err)
+		location, err := readLocation(name, *timezone)
+		if err != nil {
+			log.Fatal(err)
+		}
+		dates, err := parseDateRange(*since, *until, *period, time.Now(), location)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		errorLimit := *maxErrors
+		if *failFast {
+			errorLimit = 1
+		}
+		scanErrs := newScanErrors(errorLimit)
+
+		// Every definition uses its own credentials and settings, definitions failing to list their
+		// repositories are reported like a failed repository
+		scans := make([]*definitionScan, len(definitions))
+		readers := new(sync.WaitGroup)
+		for i, definition := range definitions {
+			readers.Add(1)
+			go func(i int, definition string) {
+				defer readers.Done()
+				client, err := newGithubClient(definition, !*noCache)
+				var repos []*github.Repository
+				if err == nil {
+					repos, err = readDefinitionRepositories(definition, *private, *repositoryPattern, client)
+				}
+				scans[i] = &definitionScan{name: definition, client: client, repos: repos}
+				if err != nil {
+					scans[i].failed = true
+					scanErrs.report(definition, []error{err})
+					return
+				}
+				fmt.Fprintln(os.Stderr, fmt.Sprintf("Read %d repositories of %s", len(repos), definition))
+			}(i, definition)
+		}
+		readers.Wait()
+
+		removeDuplicateRepositories(scans)
+
+		options := &scanOptions{
+			changelog:      *changelog,
+			changelogLimit: *changelogLimit,
+			contributors:   *contributors,
+			checksums:      *checksums,
+		}
+
+		scanned := 0
+		progress := mpb.New(mpb.WithOutput(os.Stderr))
+		scanners := new(sync.WaitGroup)
+		for _, scan := range scans {
+			if scan.failed {
+				scanned++
+				continue
 			}
-			date = d
+			scanned += len(scan.repos)
+			scanners.Add(1)
+			go func(scan *definitionScan) {
+				defer scanners.Done()
+				fetcher, err := newRepositoryFetcher(scan.name, scan.repos, scan.client)
+				if err == nil {
+					scan.resolvers, err = newDownloadResolvers(scan.name)
+				}
+				if err != nil {
+					scanErrs.report(scan.name, []error{err})
+					return
+				}
+				scan.repositories = selectRepositories(progress, scan.repos, scan.name, dates, options, scanErrs, fetcher, scan.resolvers)
+			}(scan)
 		}
+		scanners.Wait()
+		progress.Wait()
+
+		repositories := make([]*repository, 0)
+		for _, scan := range scans {
+			if *onlyNew || *markSeen {
+				store, err := state.NewStore(*homeDir, "", scan.name)
+				if err != nil {
+					log.Fatal(err)
+				}
+				scan.store = store
+			}
+
+			if *onlyNew {
+				scan.repositories = filterSeenReleases(scan.repositories, scan.store)
+			}
 
-		if u, ok := configuration.NamedSectionGet(*name, config.Remote, config.RemoteUser, ""); ok {
-			remoteAccount = u
+			scan.repositories = filterReleaseVersions(scan.repositories, minimumBump, *excludePrerelease, *latestOnly)
+			repositories = append(repositories, scan.repositories...)
 		}
-		if p, ok := configuration.NamedSectionGet(*name, config.Remote, config.ShowPrivate, ""); ok {
-			sp, err := strconv.ParseBool(p)
-			if err != nil {
-				showPrivate = false
-			} else {
-				showPrivate = sp
+
+		// Only the downloads of reported releases are verified
+		if *verifyDownloads != "off" {
+			verifier := newDownloadVerifier(*homeDir, !*noCache)
+			verifyReleaseDownloads(scans, verifier, *verifyDownloads == "require", scanErrs)
+			if err := verifier.save(); err != nil {
+				fmt.Fprintln(os.Stderr, fmt.Sprintf("Could not cache download verifications: %v", err))
+			}
+		}
+
+		printReport(os.Stdout, reportFormat, tmpl, definitions, *order, dates, repositories)
+
+		// Releases of repositories with errors may be incomplete, they are marked after a clean scan
+		if *markSeen {
+			for _, scan := range scans {
+				if err := markReleasesSeen(withoutErrors(scan.repositories, scanErrs.errors), scan.store); err != nil {
+					log.Fatal(err)
+				}
 			}
 		}
 
-		visibility := "public"
-		if showPrivate {
-			visibility = "all"
+		if len(scanErrs.errors) > 0 {
+			printErrorSummary(os.Stderr, scanErrs.errors)
+			os.Exit(errorExitCode(scanErrs.errors, scanned))
 		}
+	}
+}
 
-		fmt.Print("Reading repositories... ")
-		repos := readRepositories(*name, remoteAccount, visibility, repositoryPattern, client)
-		fmt.Println("done.")
+// Reads the repositories of a remote definition, applying its owners, visibility and pattern
+func readDefinitionRepositories(name string, private bool, repositoryPattern string, client *github.Client) ([]*github.Repository, error) {
+	remoteAccount, _ := configuration.NamedSectionGet(name, config.Remote, config.Username, "")
+	showPrivate := private
+	if r, ok := configuration.NamedSectionGet(name, config.Remote, config.RepositoryPattern, ""); ok {
+		repositoryPattern = r
+	}
 
-		repositories := selectRepositories(repos, *name, remoteAccount, date, client)
-		fmt.Println(fmt.Sprintf("Found %d repositories", len(repositories)))
-		for _, rep := range repositories {
-			for _, rel := range rep.releases {
-				if rel.milestone != nil {
-					fmt.Println(fmt.Sprintf("New %s release: %s (%s)", rep.name, rel.name, rel.created.Format("2006-01-02")))
-					fmt.Println("Release Notes: " + rel.milestoneUrl)
-					if rel.downloadUrl != "" {
-						fmt.Println("Download: " + rel.downloadUrl)
-					}
-					fmt.Println("")
+	if u, ok := configuration.NamedSectionGet(name, config.Remote, config.RemoteUser, ""); ok {
+		remoteAccount = u
+	}
+	if remoteAccount == "" {
+		return nil, fmt.Errorf("No remote user configured, please run 'grm config set %s user <user>'", name)
+	}
+	if p, ok := configuration.NamedSectionGet(name, config.Remote, config.ShowPrivate, ""); ok {
+		sp, err := strconv.ParseBool(p)
+		if err != nil {
+			showPrivate = false
+		} else {
+			showPrivate = sp
+		}
+	}
+
+	visibility := "public"
+	if showPrivate {
+		visibility = "all"
+	}
+
+	return readRepositories(name, readOwners(remoteAccount), visibility, repositoryPattern, client)
+}
+
+// Properties of the report as a whole are taken from the first definition, so merged definitions
+// have to agree on them, unless they are passed on the command line
+func checkMergedDefinitions(definitions []string, templateFile, timezone string) error {
+	keys := make([]config.Key, 0, 2)
+	if templateFile == "" {
+		keys = append(keys, config.ReportTemplate)
+	}
+	if timezone == "" {
+		keys = append(keys, config.Timezone)
+	}
+
+	for _, key := range keys {
+		first, _ := configuration.NamedSectionGet(definitions[0], config.Remote, key, "")
+		for _, definition := range definitions[1:] {
+			if value, _ := configuration.NamedSectionGet(definition, config.Remote, key, ""); value != first {
+				return fmt.Errorf("definitions %s and %s differ in %s, align them or pass it on the command line",
+					definitions[0], definition, key.Name())
+			}
+		}
+	}
+	return nil
+}
+
+// Repositories visible through multiple definitions are only scanned by the first one
+func removeDuplicateRepositories(scans []*definitionScan) {
+	known := make(map[string]bool)
+	for _, scan := range scans {
+		repos := make([]*github.Repository, 0, len(scan.repos))
+		for _, repo := range scan.repos {
+			fullName := strings.ToLower(repo.GetFullName())
+			if known[fullName] {
+				continue
+			}
+			known[fullName] = true
+			repos = append(repos, repo)
+		}
+		scan.repos = repos
+	}
+}
+
+// Only errors preventing the scan fail a repository, repositories still reported with partial
+// errors (e.g. an unverified download url) never make the run a complete failure
+func errorExitCode(repositoryErrors []*repositoryError, repositories int) int {
+	failed := make(map[string]bool)
+	for _, e := range repositoryErrors {
+		if !e.partial {
+			failed[e.repository] = true
+		}
+	}
+	if len(failed) >= repositories {
+		return exitCompleteFailure
+	}
+	return exitPartialFailure
+}
+
+func filterSeenReleases(repositories []*repository, store state.Store) []*repository {
+	filtered := make([]*repository, 0, len(repositories))
+	for _, rep := range repositories {
+		releases := make([]*release, 0, len(rep.releases))
+		for _, rel := range rep.releases {
+			if !store.Seen(repositoryFullName(rep), rel.tag) {
+				releases = append(releases, rel)
+			}
+		}
+
+		if len(releases) > 0 {
+			rep.releases = releases
+			filtered = append(filtered, rep)
+		}
+	}
+	return filtered
+}
+
+// Filters releases by their version classification, releases without a version are only
+// kept if all version bumps are reported
+func filterReleaseVersions(repositories []*repository, minBump version.Bump,
+	excludePrerelease, latestOnly bool) []*repository {
+
+	filtered := make([]*repository, 0, len(repositories))
+	for _, rep := range repositories {
+		releases := make([]*release, 0, len(rep.releases))
+		for _, rel := range rep.releases {
+			if rel.version == nil && minBump > version.Patch {
+				continue
+			}
+			if rel.version != nil && rel.bump < minBump {
+				continue
+			}
+			if excludePrerelease && isPrerelease(rel) {
+				continue
+			}
+			releases = append(releases, rel)
+		}
+
+		// Releases are sorted newest first, the latest reported release is preferred over skipped ones
+		if latestOnly && len(releases) > 1 {
+			latest := releases[0]
+			for _, rel := range releases {
+				if rel.notesUrl != "" {
+					latest = rel
+					break
 				}
 			}
+			releases = []*release{latest}
+		}
+
+		if len(releases) > 0 {
+			rep.releases = releases
+			filtered = append(filtered, rep)
 		}
 	}
+	return filtered
 }
 
-func readMilestones(account, repository string, client *github.Client) []*github.Milestone {
+func markReleasesSeen(repositories []*repository, store state.Store) error {
+	seen := make([]*state.Release, 0)
+	for _, rep := range repositories {
+		for _, rel := range reportedReleases(rep) {
+			seen = append(seen, &state.Release{
+				Repository: repositoryFullName(rep),
+				Tag:        rel.tag,
+			})
+		}
+	}
+	if err := store.Mark(seen); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, fmt.Sprintf("Marked %d releases as seen", len(seen)))
+	return nil
+}
+
+// Removes the repositories any error was reported for
+func withoutErrors(repositories []*repository, repositoryErrors []*repositoryError) []*repository {
+	failed := make(map[string]bool)
+	for _, e := range repositoryErrors {
+		failed[e.repository] = true
+	}
+
+	filtered := make([]*repository, 0, len(repositories))
+	for _, rep := range repositories {
+		if !failed[repositoryFullName(rep)] {
+			filtered = append(filtered, rep)
+		}
+	}
+	return filtered
+}
+
+func repositoryFullName(rep *repository) string {
+	return fmt.Sprintf("%s/%s", rep.owner, rep.name)
+}
+
+func readMilestones(account, repository string, client *github.Client) ([]*github.Milestone, error) {
 	ctx := context.Background()
 
 	milestones := make([]*github.Milestone, 0)
@@ -123,12 +431,12 @@ func readMilestones(account, repository string, client *github.Client) []*github
 			},
 		})
 
-		if rateLimit(response) {
-			continue
-		}
-
 		if err != nil {
-			log.Fatal(fmt.Sprintf("Could not retrieve commit for repository %s", repository), err)
+			// Repositories with disabled issues don't have milestones
+			if response != nil && response.StatusCode == http.StatusGone {
+				return milestones, nil
+			}
+			return nil, fmt.Errorf("could not retrieve milestones: %v", err)
 		}
 
 		for _, milestone := range s {
@@ -140,22 +448,30 @@ func readMilestones(account, repository string, client *github.Client) []*github
 			continue
 		}
 
-		return milestones
+		return milestones, nil
 	}
 }
 
-func selectRepositories(repositories []*github.Repository, name, account string, since time.Time, client *github.Client) []*repository {
+// Scans the repositories of a definition, each definition adds its own bar to the shared progress
+func selectRepositories(progress *mpb.Progress, repositories []*github.Repository, name string,
+	dates dateRange, options *scanOptions, scanErrs *scanErrors, fetcher repositoryFetcher,
+	resolvers *downloadResolvers) []*repository {
+
+	if len(repositories) == 0 {
+		return make([]*repository, 0)
+	}
+
 	tasks := new(sync.WaitGroup)
 	tasks.Add(len(repositories))
 
-	p := mpb.New(mpb.WithWaitGroup(tasks))
-	bar := p.AddBar(int64(len(repositories)),
+	bar := progress.AddBar(int64(len(repositories)),
 		mpb.PrependDecorators(
-			decor.Name("Filtering repositories", decor.WCSyncSpaceR),
+			decor.Name(fmt.Sprintf("Filtering %s", name), decor.WCSyncSpaceR),
 			decor.CountersNoUnit("%d / %d", decor.WCSyncWidth),
 		),
 		mpb.AppendDecorators(
 			decor.Percentage(decor.WC{W: 5}),
+			rateLimitDecorator(githubRateLimiter),
 		),
 	)
 
@@ -171,41 +487,18 @@ func selectRepositories(repositories []*github.Repository, name, account string,
 	}
 
 	for _, repo := range repositories {
-		repoName := repo.GetName()
+		repo := repo
 		jobs <- func(collector chan<- *repository) {
-			milestones := readMilestones(account, repoName, client)
-			tags := readTags(name, account, repoName, client)
-			releases := filterTags(tags, account, repoName, since, client)
-
-			var pattern *regexp.Regexp = nil
-			milestonePattern, ok := configuration.NamedSectionGet(name, config.Remote, config.MilestonePattern, repoName)
-			if !ok {
-				log.Fatal("No milestone pattern defined to extract milestone naming scheme")
-			}
-			pattern, err := regexp.Compile(milestonePattern)
-			if err != nil {
-				log.Fatal(fmt.Sprintf("Cannot compile regex: %s", milestonePattern))
-			}
-
-			downloadUrl, _ := configuration.NamedSectionGet(name, config.Remote, config.DownloadUrl, repoName)
-
-			for _, release := range releases {
-				milestone := findMatchingMilestone(release, milestones, pattern)
-				if milestone != nil {
-					release.milestone = milestone
-					release.milestoneUrl = fmt.Sprintf("%s?closed=1", milestone.GetHTMLURL())
-					release.milestoneState = milestone.GetState()
-					release.downloadUrl = buildDownloadUrl(account, repoName, downloadUrl, milestone)
+			if !scanErrs.isAborted() {
+				rep, errs := scanRepository(repo, name, dates, options, fetcher, resolvers)
+				if len(errs) > 0 && rep == nil {
+					scanErrs.report(repo.GetFullName(), errs)
+				} else if len(errs) > 0 {
+					scanErrs.reportPartial(repo.GetFullName(), errs)
 				}
-			}
-
-			if len(releases) > 0 {
-				rep := &repository{
-					name:     repoName,
-					releases: releases,
+				if rep != nil && len(rep.releases) > 0 {
+					collector <- rep
 				}
-
-				collector <- rep
 			}
 			bar.Increment()
 			tasks.Done()
@@ -213,7 +506,7 @@ func selectRepositories(repositories []*github.Repository, name, account string,
 	}
 
 	close(jobs)
-	p.Wait()
+	tasks.Wait()
 	close(collector)
 
 	reps := make([]*repository, 0)
@@ -223,25 +516,198 @@ func selectRepositories(repositories []*github.Repository, name, account string,
 		}
 	}
 
+	sort.Slice(reps, func(i, j int) bool {
+		if reps[i].name == reps[j].name {
+			return reps[i].owner < reps[j].owner
+		}
+		return reps[i].name < reps[j].name
+	})
+
 	return reps
 }
 
-func buildDownloadUrl(account, repository, downloadUrl string, milestone *github.Milestone) string {
-	downloadUrl = strings.Replace(downloadUrl, "{name}", account, -1)
-	downloadUrl = strings.Replace(downloadUrl, "{repository}", repository, -1)
-	downloadUrl = strings.Replace(downloadUrl, "{version}", milestone.GetTitle(), -1)
-	response, err := http.Get(downloadUrl)
+// Scans a single repository for releases. Errors which prevent the scan return a nil repository,
+// other errors (e.g. unreachable download urls) are returned alongside the scanned repository.
+func scanRepository(repo *github.Repository, name string, dates dateRange, options *scanOptions,
+	fetcher repositoryFetcher, resolvers *downloadResolvers) (*repository, []error) {
+
+	account := repo.GetOwner().GetLogin()
+	repoName := repo.GetName()
+	repoUrl := repo.GetHTMLURL()
+
+	milestonePattern, ok := configuration.NamedSectionGet(name, config.Remote, config.MilestonePattern, repoName)
+	if !ok {
+		return nil, []error{fmt.Errorf("no milestone pattern defined to extract milestone naming scheme")}
+	}
+	pattern, err := regexp.Compile(milestonePattern)
 	if err != nil {
-		log.Fatal("Cannot test download url")
+		return nil, []error{fmt.Errorf("cannot compile regex: %s", milestonePattern)}
+	}
+
+	milestones, err := fetcher.readMilestones(account, repoName)
+	if err != nil {
+		return nil, []error{err}
+	}
+	releases, err := readReleaseCandidates(name, account, repoName, dates, fetcher)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	resolver, err := resolvers.forRepository(repoName)
+	if err != nil {
+		return nil, []error{err}
+	}
+	links, err := resolvers.linksForRepository(repoName)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	headings := readLabelHeadings(name)
+	contributorSettings := readContributorSettings(name)
+	assetPattern, err := readAssetPattern(name, repoName)
+	if err != nil {
+		return nil, []error{err}
+	}
+	changelogSource, err := readChangelogSource(name, repoName)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	missingMilestone := "skip"
+	if m, ok := configuration.NamedSectionGet(name, config.Remote, config.MissingMilestone, repoName); ok {
+		missingMilestone = m
+	}
+
+	errs := make([]error, 0)
+	for _, release := range releases {
+		version := ""
+		milestone := findMatchingMilestone(release, milestones, pattern)
+		if milestone != nil {
+			release.milestone = milestone
+			release.milestoneUrl = fmt.Sprintf("%s?closed=1", milestone.GetHTMLURL())
+			release.milestoneState = milestone.GetState()
+			release.notesUrl = release.milestoneUrl
+			version = milestone.GetTitle()
+		} else {
+			notesUrl, err := buildMissingMilestoneUrl(missingMilestone, repoUrl, release)
+			if err != nil {
+				return nil, []error{err}
+			}
+			release.notesUrl = notesUrl
+			version = extractVersion(release, pattern)
+		}
+
+		target := &downloadTarget{
+			account:       account,
+			repository:    repoName,
+			version:       version,
+			tag:           release.tag,
+			milestone:     release.milestone.GetTitle(),
+			date:          release.created,
+			parsedVersion: release.version,
+		}
+
+		if release.notesUrl != "" && resolver != nil {
+			d, err := resolver.resolve(target)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("release %s: %v", release.tag, err))
+			}
+			if d != nil {
+				release.downloadUrl = d.url
+				release.downloadPageUrl = d.pageUrl
+			}
+		}
+
+		if release.notesUrl != "" {
+			for _, link := range links {
+				release.downloadLinks = append(release.downloadLinks, &downloadLink{name: link.name, url: link.template.render(target)})
+			}
+		}
+
+		if options.changelog && release.notesUrl != "" {
+			c, err := readReleaseChangelog(changelogSource, account, repoName, repoUrl, release,
+				headings, options.changelogLimit, fetcher)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("release %s: %v", release.tag, err))
+			}
+			release.changelog = c
+		}
+
+		// Releases read from tags may still have a Github Release carrying assets, looking it up
+		// costs a call per release, so it's only done if assets are asked for explicitly
+		if release.notesUrl != "" && release.releaseUrl == "" && (assetPattern != nil || options.checksums) {
+			githubRelease, err := fetcher.readReleaseByTag(account, repoName, release.tag)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("release %s: %v", release.tag, err))
+			} else if githubRelease != nil {
+				release.githubAssets = githubRelease.Assets
+			}
+		}
+
+		if release.notesUrl != "" && len(release.githubAssets) > 0 {
+			assets, err := readReleaseAssets(account, repoName, release.githubAssets, assetPattern, options.checksums, fetcher)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("release %s: %v", release.tag, err))
+			}
+			release.assets = assets
+		}
+
+		if options.contributors && release.notesUrl != "" {
+			c, err := readReleaseContributors(account, repoName, release, contributorSettings, fetcher)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("release %s: %v", release.tag, err))
+			}
+			release.contributors = c
+		}
 	}
-	if response.StatusCode == http.StatusOK {
-		return downloadUrl
+
+	sortReleases(releases)
+
+	rep := &repository{
+		name:       repoName,
+		owner:      account,
+		releases:   releases,
+		url:        repoUrl,
+		definition: name,
 	}
-	return ""
+	return rep, errs
+}
+
+// Builds the release notes url for releases without a matching milestone,
+// an empty url means the release is skipped in the report
+func buildMissingMilestoneUrl(missingMilestone, repositoryUrl string, release *release) (string, error) {
+	switch missingMilestone {
+	case "skip":
+		return "", nil
+	case "tag-link":
+		return fmt.Sprintf("%s/tree/%s", repositoryUrl, release.tag), nil
+	case "compare-link":
+		if release.previousTag == "" {
+			return fmt.Sprintf("%s/tree/%s", repositoryUrl, release.tag), nil
+		}
+		return fmt.Sprintf("%s/compare/%s...%s", repositoryUrl, release.previousTag, release.tag), nil
+	case "release-link":
+		if release.releaseUrl != "" {
+			return release.releaseUrl, nil
+		}
+		return fmt.Sprintf("%s/releases/tag/%s", repositoryUrl, release.tag), nil
+	}
+
+	return "", fmt.Errorf("unknown missing-milestone value: %s", missingMilestone)
+}
+
+// Extracts the version from the release tag using the milestone pattern,
+// falls back to the tag name if the pattern doesn't match
+func extractVersion(release *release, pattern *regexp.Regexp) string {
+	substrings := pattern.FindAllStringSubmatch(release.tag, 1)
+	if len(substrings) > 0 && len(substrings[0]) > 1 {
+		return substrings[0][1]
+	}
+	return release.tag
 }
 
 func findMatchingMilestone(release *release, milestones []*github.Milestone, pattern *regexp.Regexp) *github.Milestone {
-	substrings := pattern.FindAllStringSubmatch(release.name, 1)
+	substrings := pattern.FindAllStringSubmatch(release.tag, 1)
 	if len(substrings) > 0 && len(substrings[0]) > 1 {
 		milestoneName := substrings[0][1]
 		for _, milestone := range milestones {
@@ -254,65 +720,356 @@ func findMatchingMilestone(release *release, milestones []*github.Milestone, pat
 	return nil
 }
 
-func filterTags(tags []*github.RepositoryTag, account, repository string, since time.Time, client *github.Client) []*release {
+func readReleaseCandidates(name, account, repository string, dates dateRange, fetcher repositoryFetcher) ([]*release, error) {
+	releaseSource := "tags"
+	if s, ok := configuration.NamedSectionGet(name, config.Remote, config.ReleaseSource, repository); ok {
+		releaseSource = s
+	}
+
+	dateSource, err := readReleaseDateSource(name, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	switch releaseSource {
+	case "tags":
+		tags, err := fetcher.readTags(name, account, repository)
+		if err != nil {
+			return nil, err
+		}
+
+		// Publish dates of Github Releases are only read if they are used as release date
+		var published map[string]time.Time = nil
+		if dateSource == "release" || dateSource == "auto" {
+			githubReleases, err := fetcher.readReleases(name, account, repository)
+			if err != nil {
+				return nil, err
+			}
+			published = releasePublishDates(githubReleases)
+		}
+
+		releases, err := filterTags(tags, account, repository, dateSource, published, dates, fetcher)
+		if err != nil {
+			return nil, err
+		}
+		assignPreviousTags(releases, tagNames(tags))
+		classifyReleases(releases, tagNames(tags))
+		return releases, nil
+
+	case "releases":
+		githubReleases, err := fetcher.readReleases(name, account, repository)
+		if err != nil {
+			return nil, err
+		}
+		releases := filterReleases(githubReleases, dates)
+		assignPreviousTags(releases, releaseTagNames(githubReleases))
+		classifyReleases(releases, releaseTagNames(githubReleases))
+		return releases, nil
+
+	case "both":
+		githubReleases, err := fetcher.readReleases(name, account, repository)
+		if err != nil {
+			return nil, err
+		}
+		releases := filterReleases(githubReleases, dates)
+
+		known := make(map[string]bool, len(githubReleases))
+		for _, githubRelease := range githubReleases {
+			known[githubRelease.GetTagName()] = true
+		}
+
+		// Only tags without a Github release need to be resolved separately
+		allTags, err := fetcher.readTags(name, account, repository)
+		if err != nil {
+			return nil, err
+		}
+		tags := make([]*tagRef, 0)
+		for _, tag := range allTags {
+			if !known[tag.name] {
+				tags = append(tags, tag)
+			}
+		}
+		tagReleases, err := filterTags(tags, account, repository, dateSource, nil, dates, fetcher)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, tagReleases...)
+		assignPreviousTags(releases, tagNames(allTags))
+		classifyReleases(releases, tagNames(allTags))
+		return releases, nil
+	}
+
+	return nil, fmt.Errorf("unknown release source: %s", releaseSource)
+}
+
+// Assigns the previous tag of every release, based on the newest-first order of the given tag names
+func assignPreviousTags(releases []*release, tagNames []string) {
+	previousTags := make(map[string]string, len(tagNames))
+	for i := 0; i < len(tagNames)-1; i++ {
+		previousTags[tagNames[i]] = tagNames[i+1]
+	}
+	for _, release := range releases {
+		release.previousTag = previousTags[release.tag]
+	}
+}
+
+// Classifies every release against the closest lower final release of the repository,
+// tags which aren't a version are neither classified nor used as previous version
+func classifyReleases(releases []*release, tagNames []string) {
+	versions := make([]*version.Version, 0, len(tagNames))
+	for _, tagName := range tagNames {
+		if v, err := version.Parse(tagName); err == nil && !v.IsPrerelease() {
+			versions = append(versions, v)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return version.Compare(versions[i], versions[j]) < 0
+	})
+
+	for _, release := range releases {
+		v, err := version.Parse(release.tag)
+		if err != nil {
+			continue
+		}
+
+		var previous *version.Version = nil
+		for _, candidate := range versions {
+			if version.Compare(candidate, v) >= 0 {
+				break
+			}
+			previous = candidate
+		}
+
+		release.version = v
+		release.bump = version.Classify(previous, v)
+	}
+}
+
+// Sorts releases newest version first, releases without version follow newest first
+func sortReleases(releases []*release) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		a, b := releases[i], releases[j]
+		switch {
+		case a.version != nil && b.version != nil:
+			return version.Compare(a.version, b.version) > 0
+		case a.version != nil:
+			return true
+		case b.version != nil:
+			return false
+		}
+		return a.created.After(b.created)
+	})
+}
+
+func isPrerelease(release *release) bool {
+	return release.prerelease || (release.version != nil && release.version.IsPrerelease())
+}
+
+// Describes the kind of release: major, minor, patch or pre-release, empty if the tag isn't a version
+func releaseClassification(release *release) string {
+	switch {
+	case release.version == nil:
+		return ""
+	case isPrerelease(release):
+		return "pre-release"
+	}
+	return release.bump.String()
+}
+
+func tagNames(tags []*tagRef) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.name)
+	}
+	return names
+}
+
+func releaseTagNames(githubReleases []*github.RepositoryRelease) []string {
+	names := make([]string, 0, len(githubReleases))
+	for _, githubRelease := range githubReleases {
+		names = append(names, githubRelease.GetTagName())
+	}
+	return names
+}
+
+func filterReleases(githubReleases []*github.RepositoryRelease, dates dateRange) []*release {
+	filteredReleases := make([]*release, 0)
+	for _, githubRelease := range githubReleases {
+		published := githubRelease.GetPublishedAt().Time
+		if published.IsZero() {
+			published = githubRelease.GetCreatedAt().Time
+		}
+
+		if dates.contains(published) {
+			releaseName := githubRelease.GetName()
+			if releaseName == "" {
+				releaseName = githubRelease.GetTagName()
+			}
+
+			filteredReleases = append(filteredReleases, &release{
+				created:      published,
+				name:         releaseName,
+				tag:          githubRelease.GetTagName(),
+				releaseUrl:   githubRelease.GetHTMLURL(),
+				body:         githubRelease.GetBody(),
+				published:    githubRelease.GetPublishedAt().Time,
+				draft:        githubRelease.GetDraft(),
+				prerelease:   githubRelease.GetPrerelease(),
+				author:       githubRelease.GetAuthor().GetLogin(),
+				githubAssets: githubRelease.Assets,
+			})
+		}
+	}
+
+	return filteredReleases
+}
+
+func filterTags(tags []*tagRef, account, repository, dateSource string, published map[string]time.Time,
+	dates dateRange, fetcher repositoryFetcher) ([]*release, error) {
+
 	filteredTags := make([]*release, 0)
 	for _, tag := range tags {
-		commit := readCommit(account, repository, tag.GetCommit().GetSHA(), client)
-		if since.Before(commit.GetCommit().GetCommitter().GetDate()) {
+		created, err := readReleaseDate(dateSource, account, repository, tag, published, fetcher)
+		if err != nil {
+			return nil, err
+		}
+		if dates.contains(created) {
 			filteredTags = append(filteredTags, &release{
-				created: commit.GetCommit().GetCommitter().GetDate(),
-				name:    tag.GetName(),
+				created: created,
+				name:    tag.name,
+				tag:     tag.name,
 			})
 		}
 	}
 
-	return filteredTags
+	return filteredTags, nil
 }
 
-func readCommit(account, repository, sha string, client *github.Client) *github.RepositoryCommit {
-	ctx := context.Background()
+func readReleaseDateSource(name, repository string) (string, error) {
+	dateSource := "commit"
+	if s, ok := configuration.NamedSectionGet(name, config.Remote, config.ReleaseDateSource, repository); ok && s != "" {
+		dateSource = s
+	}
 
-	commit, response, err := client.Repositories.GetCommit(ctx, account, repository, sha)
-	for {
-		if rateLimit(response) {
-			continue
+	switch dateSource {
+	case "commit", "tag", "release", "auto":
+		return dateSource, nil
+	}
+	return "", fmt.Errorf("unknown release date source: %s", dateSource)
+}
+
+// Resolves the release date of a tag, falling back to the commit date if the selected date isn't
+// available, e.g. for lightweight tags without tagger date. The auto date source prefers the
+// publish date of a Github Release over the tagger date over the commit date.
+func readReleaseDate(dateSource, account, repository string, tag *tagRef, published map[string]time.Time,
+	fetcher repositoryFetcher) (time.Time, error) {
+
+	if dateSource == "release" || dateSource == "auto" {
+		if date, ok := published[tag.name]; ok {
+			return date, nil
 		}
+	}
 
+	if dateSource == "tag" || dateSource == "auto" {
+		date, err := fetcher.readTagDate(account, repository, tag)
 		if err != nil {
-			log.Fatal(fmt.Sprintf("Could not retrieve commit for commitId %s: ", sha), err)
+			return time.Time{}, err
 		}
+		if !date.IsZero() {
+			return date, nil
+		}
+	}
 
-		return commit
+	return fetcher.readCommitDate(account, repository, tag)
+}
+
+// Maps the tag names of all published Github Releases to their publish date
+func releasePublishDates(githubReleases []*github.RepositoryRelease) map[string]time.Time {
+	published := make(map[string]time.Time, len(githubReleases))
+	for _, githubRelease := range githubReleases {
+		if date := githubRelease.GetPublishedAt().Time; !date.IsZero() {
+			published[githubRelease.GetTagName()] = date
+		}
 	}
+	return published
 }
 
-func readTags(name, account, repository string, client *github.Client) []*github.RepositoryTag {
+func readCommit(account, repository, sha string, client *github.Client) (*github.RepositoryCommit, error) {
 	ctx := context.Background()
 
-	releases := make([]*github.RepositoryTag, 0)
+	commit, _, err := client.Repositories.GetCommit(ctx, account, repository, sha)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve commit for commitId %s: %v", sha, err)
+	}
 
-	var pattern *regexp.Regexp = nil
-	if r, ok := configuration.NamedSectionGet(name, config.Remote, config.ReleasePattern, repository); ok {
+	return commit, nil
+}
+
+func readReleasePattern(name, repository string) (*regexp.Regexp, error) {
+	if r, ok := configuration.NamedSectionGet(name, config.Remote, config.ReleasePattern, repository); ok && r != "" {
 		p, err := regexp.Compile(r)
 		if err != nil {
-			log.Fatal(fmt.Sprintf("Cannot compile regex: %s", r))
+			return nil, fmt.Errorf("cannot compile regex: %s", r)
 		}
-		pattern = p
+		return p, nil
+	}
+	return nil, nil
+}
+
+func readReleases(name, account, repository string, client *github.Client) ([]*github.RepositoryRelease, error) {
+	ctx := context.Background()
+
+	releases := make([]*github.RepositoryRelease, 0)
+	pattern, err := readReleasePattern(name, repository)
+	if err != nil {
+		return nil, err
 	}
 
 	page := 1
 	for {
-		r, response, err := client.Repositories.ListTags(ctx, account, repository, &github.ListOptions{
+		r, response, err := client.Repositories.ListReleases(ctx, account, repository, &github.ListOptions{
 			PerPage: 100,
 			Page:    page,
 		})
 
-		if rateLimit(response) {
+		if err != nil {
+			return nil, fmt.Errorf("could not retrieve releases: %v", err)
+		}
+
+		for _, release := range r {
+			if pattern != nil && !pattern.MatchString(release.GetTagName()) {
+				continue
+			}
+			releases = append(releases, release)
+		}
+
+		if hasMorePages(response) {
+			page++
 			continue
 		}
 
+		return releases, nil
+	}
+}
+
+func readTags(name, account, repository string, client *github.Client) ([]*github.RepositoryTag, error) {
+	ctx := context.Background()
+
+	releases := make([]*github.RepositoryTag, 0)
+	pattern, err := readReleasePattern(name, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	page := 1
+	for {
+		r, response, err := client.Repositories.ListTags(ctx, account, repository, &github.ListOptions{
+			PerPage: 100,
+			Page:    page,
+		})
+
 		if err != nil {
-			log.Fatal(fmt.Sprintf("Could not retrieve tags for repository %s: ", repository), err)
+			return nil, fmt.Errorf("could not retrieve tags: %v", err)
 		}
 
 		for _, release := range r {
@@ -327,12 +1084,12 @@ func readTags(name, account, repository string, client *github.Client) []*github
 			continue
 		}
 
-		return releases
+		return releases, nil
 	}
 }
 
-func readRepositories(name, account, visibility, repositoryPattern string, client *github.Client) []*github.Repository {
-	ctx := context.Background()
+func readRepositories(name string, owners []string, visibility, repositoryPattern string,
+	client *github.Client) ([]*github.Repository, error) {
 
 	repositories := make([]*github.Repository, 0)
 
@@ -340,11 +1097,56 @@ func readRepositories(name, account, visibility, repositoryPattern string, clien
 	if repositoryPattern != "" {
 		p, err := regexp.Compile(repositoryPattern)
 		if err != nil {
-			log.Fatal(fmt.Sprintf("Cannot compile regex: %s", repositoryPattern))
+			return nil, fmt.Errorf("Cannot compile regex: %s", repositoryPattern)
 		}
 		pattern = p
 	}
 
+	// Repositories might be visible through multiple owners, e.g. a user being member of an organization
+	known := make(map[string]bool)
+	for _, owner := range owners {
+		ownerType, err := readOwnerType(name, owner, client)
+		if err != nil {
+			return nil, err
+		}
+
+		var r []*github.Repository
+		if ownerType == "org" {
+			r, err = readOrganizationRepositories(owner, visibility, client)
+		} else {
+			r, err = readUserRepositories(owner, visibility, client)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repository := range r {
+			fullName := strings.ToLower(repository.GetFullName())
+			if known[fullName] {
+				continue
+			}
+			known[fullName] = true
+
+			if pattern == nil || pattern.MatchString(repository.GetName()) {
+				blacklisted, err := isBlacklisted(name, repository.GetName())
+				if err != nil {
+					return nil, err
+				}
+				if !blacklisted {
+					repositories = append(repositories, repository)
+				}
+			}
+		}
+	}
+
+	return repositories, nil
+}
+
+func readUserRepositories(account, visibility string, client *github.Client) ([]*github.Repository, error) {
+	ctx := context.Background()
+
+	repositories := make([]*github.Repository, 0)
+
 	page := 1
 	for {
 		r, response, err := client.Repositories.List(ctx, account, &github.RepositoryListOptions{
@@ -357,53 +1159,203 @@ func readRepositories(name, account, visibility, repositoryPattern string, clien
 			},
 		})
 
-		if rateLimit(response) {
+		if err != nil {
+			return nil, fmt.Errorf("Could not retrieve repositories: %v", err)
+		}
+
+		repositories = append(repositories, r...)
+
+		if hasMorePages(response) {
+			page++
 			continue
 		}
 
+		return repositories, nil
+	}
+}
+
+func readOrganizationRepositories(organization, visibility string, client *github.Client) ([]*github.Repository, error) {
+	ctx := context.Background()
+
+	repositories := make([]*github.Repository, 0)
+
+	repositoryType := "public"
+	if visibility == "all" {
+		repositoryType = "all"
+	}
+
+	page := 1
+	for {
+		r, response, err := client.Repositories.ListByOrg(ctx, organization, &github.RepositoryListByOrgOptions{
+			Type: repositoryType,
+			ListOptions: github.ListOptions{
+				PerPage: 100,
+				Page:    page,
+			},
+		})
+
 		if err != nil {
-			log.Fatal("Could not retrieve repositories: ", err)
+			return nil, fmt.Errorf("Could not retrieve repositories of organization %s: %v", organization, err)
 		}
 
-		for _, repository := range r {
-			if pattern == nil || pattern.MatchString(repository.GetName()) {
-				if !isBlacklisted(name, repository.GetName()) {
-					repositories = append(repositories, repository)
-				}
-			}
-		}
+		repositories = append(repositories, r...)
 
 		if hasMorePages(response) {
 			page++
 			continue
 		}
 
-		return repositories
+		return repositories, nil
+	}
+}
+
+// Reads the list of comma separated owners (users or organizations) of a remote definition
+func readOwners(remoteAccount string) []string {
+	owners := make([]string, 0)
+	for _, owner := range strings.Split(remoteAccount, ",") {
+		owner = strings.TrimSpace(owner)
+		if owner != "" {
+			owners = append(owners, owner)
+		}
 	}
+	return owners
 }
 
-func isBlacklisted(name, repository string) bool {
+// Reads the configured owner type (user, org), auto detects the type if not configured
+func readOwnerType(name, owner string, client *github.Client) (string, error) {
+	ownerType := "auto"
+	if t, ok := configuration.NamedSectionGet(name, config.Remote, config.OwnerType, owner); ok && t != "" {
+		ownerType = t
+	}
+
+	switch ownerType {
+	case "user", "org":
+		return ownerType, nil
+	case "auto":
+		user, _, err := client.Users.Get(context.Background(), owner)
+		if err != nil {
+			return "", fmt.Errorf("could not retrieve owner %s: %v", owner, err)
+		}
+
+		if user.GetType() == "Organization" {
+			return "org", nil
+		}
+		return "user", nil
+	}
+
+	return "", fmt.Errorf("unknown owner type '%s' for owner %s", ownerType, owner)
+}
+
+func isBlacklisted(name, repository string) (bool, error) {
 	if r, ok := configuration.NamedSectionGet(name, config.Remote, config.RepositoryBlacklisted, repository); ok {
 		b, err := strconv.ParseBool(r)
 		if err != nil {
-			log.Fatal("Could not parse boolean: ", err)
+			return false, fmt.Errorf("could not parse repository-blacklisted of %s: %v", repository, err)
 		}
-		return b
+		return b, nil
 	}
-	return false
+	return false, nil
+}
+
+type repositoryError struct {
+	repository string
+	err        error
+	// The repository was still reported, only parts of it are missing
+	partial bool
 }
 
 type repository struct {
-	name     string
-	releases []*release
-	url      string
+	name       string
+	owner      string
+	releases   []*release
+	url        string
+	definition string
+}
+
+// Options of the report affecting the scan of every repository
+type scanOptions struct {
+	changelog      bool
+	changelogLimit int
+	contributors   bool
+	checksums      bool
+}
+
+// Repositories and scan results of a single remote definition
+type definitionScan struct {
+	name         string
+	client       *github.Client
+	repos        []*github.Repository
+	repositories []*repository
+	store        state.Store
+	resolvers    *downloadResolvers
+	failed       bool
+}
+
+// Collects the errors of all scanned repositories, the scan is aborted when too many errors were found
+type scanErrors struct {
+	mutex     sync.Mutex
+	errors    []*repositoryError
+	maxErrors int
+	aborted   bool
+}
+
+func newScanErrors(maxErrors int) *scanErrors {
+	return &scanErrors{
+		errors:    make([]*repositoryError, 0),
+		maxErrors: maxErrors,
+	}
+}
+
+func (e *scanErrors) report(repository string, errs []error) {
+	e.add(repository, errs, false)
+}
+
+// Reports errors of a repository which is reported nonetheless
+func (e *scanErrors) reportPartial(repository string, errs []error) {
+	e.add(repository, errs, true)
+}
+
+func (e *scanErrors) add(repository string, errs []error, partial bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for _, err := range errs {
+		e.errors = append(e.errors, &repositoryError{repository: repository, err: err, partial: partial})
+	}
+	if e.maxErrors > 0 && len(e.errors) >= e.maxErrors {
+		e.aborted = true
+	}
+}
+
+func (e *scanErrors) isAborted() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.aborted
 }
 
 type release struct {
-	name           string
-	created        time.Time
-	milestoneUrl   string
-	milestoneState string
-	downloadUrl    string
-	milestone      *github.Milestone
+	name                 string
+	tag                  string
+	created              time.Time
+	milestoneUrl         string
+	notesUrl             string
+	previousTag          string
+	milestoneState       string
+	downloadUrl          string
+	downloadPageUrl      string
+	downloadVerification *verification
+	downloadLinks        []*downloadLink
+	milestone            *github.Milestone
+	releaseUrl           string
+	body                 string
+	published            time.Time
+	draft                bool
+	prerelease           bool
+	author               string
+	githubAssets         []github.ReleaseAsset
+	assets               []*releaseAsset
+	version              *version.Version
+	bump                 version.Bump
+	changelog            *changelog
+	contributors         []*contributor
+	comparison           *github.CommitsComparison
 }
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"testing"
+	"errors"
+	"strings"
+	"github.com/google/go-github/github"
+)
+
+func TestErrorExitCode(t *testing.T) {
+	failure := errors.New("failure")
+	tests := []struct {
+		name         string
+		failed       []string
+		partial      []string
+		repositories int
+		want         int
+	}{
+		{"single failed repository", []string{"a/one"}, nil, 3, exitPartialFailure},
+		{"multiple errors of one repository", []string{"a/one", "a/one"}, nil, 2, exitPartialFailure},
+		{"all repositories failed", []string{"a/one", "a/two"}, nil, 2, exitCompleteFailure},
+		{"failed definition without repositories", []string{"definition"}, nil, 1, exitCompleteFailure},
+		{"failed definition next to scanned repositories", []string{"definition"}, nil, 4, exitPartialFailure},
+		// Repositories reported with unverified downloads or missing changelogs didn't fail
+		{"partial errors of all repositories", nil, []string{"a/one", "a/two"}, 2, exitPartialFailure},
+		{"partial errors next to failed repository", []string{"a/one"}, []string{"a/two"}, 2, exitPartialFailure},
+	}
+
+	for _, test := range tests {
+		errs := make([]*repositoryError, 0)
+		for _, repository := range test.failed {
+			errs = append(errs, &repositoryError{repository: repository, err: failure})
+		}
+		for _, repository := range test.partial {
+			errs = append(errs, &repositoryError{repository: repository, err: failure, partial: true})
+		}
+		if got := errorExitCode(errs, test.repositories); got != test.want {
+			t.Errorf("%s: exit code = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestWithoutErrors(t *testing.T) {
+	failure := errors.New("failure")
+	repositories := []*repository{
+		{owner: "a", name: "one"},
+		{owner: "a", name: "two"},
+		{owner: "b", name: "one"},
+	}
+
+	tests := []struct {
+		name   string
+		failed []string
+		want   []string
+	}{
+		{"no errors", nil, []string{"a/one", "a/two", "b/one"}},
+		{"failed repository", []string{"a/two", "a/two"}, []string{"a/one", "b/one"}},
+		{"failed definition", []string{"definition"}, []string{"a/one", "a/two", "b/one"}},
+	}
+
+	for _, test := range tests {
+		errs := make([]*repositoryError, 0)
+		for _, repository := range test.failed {
+			errs = append(errs, &repositoryError{repository: repository, err: failure})
+		}
+		got := make([]string, 0)
+		for _, rep := range withoutErrors(repositories, errs) {
+			got = append(got, repositoryFullName(rep))
+		}
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%s: repositories = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestIsBlacklisted(t *testing.T) {
+	defer setupTestConfiguration(t, "[Remote \"foo\"]\nrepository-blacklisted:hidden=true\n"+
+		"repository-blacklisted:shown=false\nrepository-blacklisted:broken=maybe\n")()
+
+	tests := []struct {
+		repository string
+		want       bool
+		err        bool
+	}{
+		{"hidden", true, false},
+		{"shown", false, false},
+		{"other", false, false},
+		{"broken", false, true},
+	}
+
+	for _, test := range tests {
+		blacklisted, err := isBlacklisted("foo", test.repository)
+		if (err != nil) != test.err {
+			t.Errorf("%s: error = %v, want error %v", test.repository, err, test.err)
+		}
+		if blacklisted != test.want {
+			t.Errorf("%s: blacklisted = %v, want %v", test.repository, blacklisted, test.want)
+		}
+	}
+}
+
+func TestClassifyReleases(t *testing.T) {
+	tags := []string{"v2.0.0", "v1.3.0-rc.1", "v1.2.1", "v1.2.0", "v1.10.0", "v1.0.0", "nightly"}
+	releases := []*release{
+		{tag: "v2.0.0"}, {tag: "v1.10.0"}, {tag: "v1.3.0-rc.1"}, {tag: "v1.2.1"},
+		{tag: "v1.2.0"}, {tag: "v1.0.0"}, {tag: "nightly"},
+	}
+	classifyReleases(releases, tags)
+
+	// Pre-releases are never used as previous version, v1.10.0 follows v1.2.1
+	want := map[string]string{
+		"v2.0.0":      "major",
+		"v1.10.0":     "minor",
+		"v1.3.0-rc.1": "pre-release",
+		"v1.2.1":      "patch",
+		"v1.2.0":      "minor",
+		"v1.0.0":      "major",
+		"nightly":     "",
+	}
+	for _, release := range releases {
+		if got := releaseClassification(release); got != want[release.tag] {
+			t.Errorf("%s: classification = %q, want %q", release.tag, got, want[release.tag])
+		}
+	}
+}
+
+func TestScanRepositoryReadsTagReleaseAssets(t *testing.T) {
+	repo := &github.Repository{
+		Name:    github.String("example"),
+		HTMLURL: github.String("https://github.com/noctarius/example"),
+		Owner:   &github.User{Login: github.String("noctarius")},
+	}
+
+	tests := []struct {
+		name      string
+		settings  string
+		checksums bool
+		assets    int
+	}{
+		{"assets not asked for", "", false, 0},
+		{"asset pattern", "asset-pattern=\\.zip$\n", false, 1},
+		{"checksums", "", true, 2},
+	}
+
+	for _, test := range tests {
+		restore := setupTestConfiguration(t, "[Remote \"foo\"]\nmilestone-pattern=(.*)\nmissing-milestone=tag-link\n"+test.settings)
+		fetcher := &fakeFetcher{
+			tags: []*tagRef{{name: "v1.0.0", committed: testNow}},
+			tagReleases: map[string]*github.RepositoryRelease{"v1.0.0": {Assets: []github.ReleaseAsset{
+				{Name: github.String("example.zip")},
+				{Name: github.String("example.tar.gz")},
+			}}},
+		}
+		resolvers, err := newDownloadResolvers("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rep, errs := scanRepository(repo, "foo", dateRange{}, &scanOptions{checksums: test.checksums}, fetcher, resolvers)
+		restore()
+		if len(errs) > 0 || rep == nil || len(rep.releases) != 1 {
+			t.Errorf("%s: scan = %v, %v", test.name, rep, errs)
+			continue
+		}
+		if assets := len(rep.releases[0].assets); assets != test.assets {
+			t.Errorf("%s: %d assets, want %d", test.name, assets, test.assets)
+		}
+	}
+}
+
+func TestCheckMergedDefinitions(t *testing.T) {
+	defer setupTestConfiguration(t, "[Remote \"a\"]\nreport-template=weekly.tmpl\ntimezone=Europe/Berlin\n"+
+		"[Remote \"b\"]\nreport-template=weekly.tmpl\ntimezone=Europe/Berlin\n"+
+		"[Remote \"c\"]\nreport-template=monthly.tmpl\ntimezone=Europe/Berlin\n"+
+		"[Remote \"d\"]\nreport-template=weekly.tmpl\n")()
+
+	tests := []struct {
+		name         string
+		definitions  []string
+		templateFile string
+		timezone     string
+		err          bool
+	}{
+		{"single definition", []string{"c"}, "", "", false},
+		{"same properties", []string{"a", "b"}, "", "", false},
+		{"different templates", []string{"a", "b", "c"}, "", "", true},
+		{"template passed on command line", []string{"a", "c"}, "report.tmpl", "", false},
+		{"missing timezone", []string{"a", "d"}, "", "", true},
+		{"timezone passed on command line", []string{"a", "d"}, "", "UTC", false},
+	}
+
+	for _, test := range tests {
+		err := checkMergedDefinitions(test.definitions, test.templateFile, test.timezone)
+		if (err != nil) != test.err {
+			t.Errorf("%s: error = %v, want error %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestRemoveDuplicateRepositories(t *testing.T) {
+	repos := func(names ...string) []*github.Repository {
+		result := make([]*github.Repository, 0, len(names))
+		for _, name := range names {
+			result = append(result, &github.Repository{FullName: github.String(name)})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name  string
+		repos [][]string
+		want  [][]string
+	}{
+		{"distinct repositories", [][]string{{"a/one"}, {"b/one"}}, [][]string{{"a/one"}, {"b/one"}}},
+		{"first definition wins", [][]string{{"a/one", "a/two"}, {"a/two", "b/one"}, {"a/one"}},
+			[][]string{{"a/one", "a/two"}, {"b/one"}, {}}},
+		{"names differing in case", [][]string{{"a/One"}, {"A/one"}}, [][]string{{"a/One"}, {}}},
+		{"failed definition without repositories", [][]string{{}, {"a/one"}}, [][]string{{}, {"a/one"}}},
+	}
+
+	for _, test := range tests {
+		scans := make([]*definitionScan, 0, len(test.repos))
+		for _, names := range test.repos {
+			scans = append(scans, &definitionScan{repos: repos(names...)})
+		}
+		removeDuplicateRepositories(scans)
+
+		for i, scan := range scans {
+			got := make([]string, 0, len(scan.repos))
+			for _, repo := range scan.repos {
+				got = append(got, repo.GetFullName())
+			}
+			if strings.Join(got, ",") != strings.Join(test.want[i], ",") {
+				t.Errorf("%s: definition %d scans %v, want %v", test.name, i, got, test.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"github.com/jawher/mow.cli"
+	"log"
+	"fmt"
+	"grm/state"
+)
+
+func cmdState(cmd *cli.Cmd) {
+	cmd.Command("list", "Lists the releases already reported for a remote definition", cmdStateList)
+	cmd.Command("reset", "Resets the reported releases of a remote definition", cmdStateReset)
+	cmd.Command("rollback", "Rolls back the most recent runs marking releases as reported", cmdStateRollback)
+}
+
+func cmdStateList(cmd *cli.Cmd) {
+	cmd.Spec = "NAME [ -v ]"
+
+	var (
+		name    = cmd.StringArg("NAME", "", "The name of the remote definition")
+		verbose = cmd.BoolOpt("v verbose", false, "List all releases of every run")
+	)
+
+	cmd.Action = func() {
+		if *name == "" {
+			log.Fatal("No name specified")
+		}
+
+		store, err := state.NewStore(*homeDir, "", *name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		runs := store.Runs()
+		if len(runs) == 0 {
+			fmt.Println("No releases marked as reported")
+			return
+		}
+
+		for _, run := range runs {
+			fmt.Println(fmt.Sprintf("Run %d (%s): %d releases", run.Id, run.Marked.Format("2006-01-02 15:04:05"), len(run.Releases)))
+			if *verbose {
+				for _, release := range run.Releases {
+					fmt.Println(fmt.Sprintf("\t%s %s", release.Repository, release.Tag))
+				}
+			}
+		}
+	}
+}
+
+func cmdStateReset(cmd *cli.Cmd) {
+	cmd.Spec = "NAME [ --yes ]"
+
+	var (
+		name = cmd.StringArg("NAME", "", "The name of the remote definition")
+		yes  = cmd.BoolOpt("y yes", false, "Accept all questions with yes")
+	)
+
+	cmd.Action = func() {
+		if *name == "" {
+			log.Fatal("No name specified")
+		}
+
+		readResetConfirm := func() bool {
+			if *yes {
+				return true
+			}
+			return readYesNoQuestion(fmt.Sprintf("All reported releases of %s are about to be forgotten. Do you "+
+				"really want to continue?", *name), false)
+		}
+
+		if !readResetConfirm() {
+			// Stop execution
+			fmt.Println("State not changed")
+			return
+		}
+
+		store, err := state.NewStore(*homeDir, "", *name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := store.Reset(); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("State reset")
+	}
+}
+
+func cmdStateRollback(cmd *cli.Cmd) {
+	cmd.Spec = "NAME [ --runs=<runs> ]"
+
+	var (
+		name = cmd.StringArg("NAME", "", "The name of the remote definition")
+		runs = cmd.IntOpt("runs", 1, "The number of most recent runs to roll back, default: 1")
+	)
+
+	cmd.Action = func() {
+		if *name == "" {
+			log.Fatal("No name specified")
+		}
+
+		store, err := state.NewStore(*homeDir, "", *name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		removed, err := store.Rollback(*runs)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(fmt.Sprintf("Rolled back %d runs", removed))
+	}
+}
@@ -85,30 +85,94 @@ var sectionLookup = map[string]Section{
 }
 
 var (
-	Username          Key = key{"username", false, false}
-	Password          Key = key{"password", false, false}
-	Salt              Key = key{"salt", false, false}
-	RemoteUser        Key = key{"user", false, true}
-	ShowPrivate       Key = key{"show-private", false, true}
-	RepositoryPattern Key = key{"repository-pattern", false, true}
+	Username           Key = key{"username", false, false}
+	Password           Key = key{"password", false, false}
+	Salt               Key = key{"salt", false, false}
+	AuthType           Key = key{"auth-type", false, false}
+	Token              Key = key{"token", false, false}
+	AppId              Key = key{"app-id", false, false}
+	InstallationId     Key = key{"installation-id", false, false}
+	PrivateKey         Key = key{"private-key", false, false}
+	RemoteUser         Key = key{"user", false, true}
+	ShowPrivate        Key = key{"show-private", false, true}
+	RepositoryPattern  Key = key{"repository-pattern", false, true}
+	ReportTemplate     Key = key{"report-template", false, true}
+	OAuthClientId      Key = key{"oauth-client-id", false, true}
+	OAuthUrl           Key = key{"oauth-url", false, true}
+	ApiUrl             Key = key{"api-url", false, true}
+	UploadUrl          Key = key{"upload-url", false, true}
+	CaBundle           Key = key{"ca-bundle", false, true}
+	Proxy              Key = key{"proxy", false, true}
+	ApiMode            Key = key{"api-mode", false, true}
+	Timezone           Key = key{"timezone", false, true}
+	ChangelogLabels    Key = key{"changelog-labels", false, true}
+	ContributorsIgnore Key = key{"contributors-ignore", false, true}
+	ContributorAliases Key = key{"contributor-aliases", false, true}
+	MavenUrl           Key = key{"maven-url", false, true}
+	NpmUrl             Key = key{"npm-url", false, true}
+	PypiUrl            Key = key{"pypi-url", false, true}
+	NugetUrl           Key = key{"nuget-url", false, true}
+	CratesUrl          Key = key{"crates-url", false, true}
+	Schedule           Key = key{"schedule", false, true}
+	Notify             Key = key{"notify", false, true}
 
 	ReleasePattern        Key = key{"release-pattern", true, true}
+	ReleaseSource         Key = key{"release-source", true, true}
+	ReleaseDateSource     Key = key{"release-date-source", true, true}
 	MilestonePattern      Key = key{"milestone-pattern", true, true}
+	ChangelogSource       Key = key{"changelog-source", true, true}
+	MissingMilestone      Key = key{"missing-milestone", true, true}
+	OwnerType             Key = key{"owner-type", true, true}
 	RepositoryBlacklisted Key = key{"repository-blacklisted", true, true}
 	DownloadUrl           Key = key{"download-url", true, true}
+	DownloadResolver      Key = key{"download-resolver", true, true}
+	AssetPattern          Key = key{"asset-pattern", true, true}
+	DownloadLinks         Key = key{"download-links", true, true}
 )
 
 var keyLookup = map[string]Key{
 	Username.Name():              Username,
 	Password.Name():              Password,
 	Salt.Name():                  Salt,
+	AuthType.Name():              AuthType,
+	Token.Name():                 Token,
+	AppId.Name():                 AppId,
+	InstallationId.Name():        InstallationId,
+	PrivateKey.Name():            PrivateKey,
 	RemoteUser.Name():            RemoteUser,
 	ShowPrivate.Name():           ShowPrivate,
 	RepositoryPattern.Name():     RepositoryPattern,
+	ReportTemplate.Name():        ReportTemplate,
+	OAuthClientId.Name():         OAuthClientId,
+	OAuthUrl.Name():              OAuthUrl,
+	ApiUrl.Name():                ApiUrl,
+	UploadUrl.Name():             UploadUrl,
+	CaBundle.Name():              CaBundle,
+	Proxy.Name():                 Proxy,
+	ApiMode.Name():               ApiMode,
+	Timezone.Name():              Timezone,
+	ChangelogLabels.Name():       ChangelogLabels,
+	ContributorsIgnore.Name():    ContributorsIgnore,
+	ContributorAliases.Name():    ContributorAliases,
+	MavenUrl.Name():              MavenUrl,
+	NpmUrl.Name():                NpmUrl,
+	PypiUrl.Name():               PypiUrl,
+	NugetUrl.Name():              NugetUrl,
+	CratesUrl.Name():             CratesUrl,
+	Schedule.Name():              Schedule,
+	Notify.Name():                Notify,
 	ReleasePattern.Name():        ReleasePattern,
+	ReleaseSource.Name():         ReleaseSource,
+	ReleaseDateSource.Name():     ReleaseDateSource,
 	MilestonePattern.Name():      MilestonePattern,
+	ChangelogSource.Name():       ChangelogSource,
+	MissingMilestone.Name():      MissingMilestone,
+	OwnerType.Name():             OwnerType,
 	RepositoryBlacklisted.Name(): RepositoryBlacklisted,
 	DownloadUrl.Name():           DownloadUrl,
+	DownloadResolver.Name():      DownloadResolver,
+	AssetPattern.Name():          AssetPattern,
+	DownloadLinks.Name():         DownloadLinks,
 }
 
 func NewConfiguration(homeDir string) Configuration {
@@ -142,6 +206,15 @@ func SectionLookup(section string) Section {
 	return sectionLookup[tokens[0]]
 }
 
+// Extracts the name of a named section, e.g. example from Remote "example"
+func ExtractSectionName(section string) string {
+	tokens := strings.SplitN(section, " \"", 2)
+	if len(tokens) < 2 {
+		return ""
+	}
+	return strings.TrimSuffix(tokens[1], "\"")
+}
+
 func ExtractSpecifier(key string) string {
 	tokens := strings.Split(key, ":")
 	if len(tokens) > 1 {
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+func TestExtractSectionName(t *testing.T) {
+	tests := []struct {
+		section string
+		want    string
+	}{
+		{`Remote "example"`, "example"},
+		{`Remote "with space"`, "with space"},
+		{`Remote ""`, ""},
+		{"Remote", ""},
+	}
+
+	for _, test := range tests {
+		if got := ExtractSectionName(test.section); got != test.want {
+			t.Errorf("ExtractSectionName(%q) = %q, want %q", test.section, got, test.want)
+		}
+	}
+}
+
+func TestNamedSectionsResolveByName(t *testing.T) {
+	homeDir, err := ioutil.TempDir("", "grm-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(homeDir)
+
+	grmPath := filepath.Join(homeDir, "github-release-monitor")
+	if err := os.MkdirAll(grmPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	content := "[Remote \"foo\"]\nuser=noctarius\n[Remote \"bar\"]\nuser=hazelcast\n"
+	if err := ioutil.WriteFile(filepath.Join(grmPath, "config"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	configuration := NewConfiguration(homeDir)
+	names := make([]string, 0)
+	for _, section := range configuration.NamedSections(Remote) {
+		names = append(names, ExtractSectionName(section))
+	}
+	sort.Strings(names)
+
+	want := map[string]string{"bar": "hazelcast", "foo": "noctarius"}
+	if len(names) != len(want) {
+		t.Fatalf("NamedSections returned %v, want %d definitions", names, len(want))
+	}
+	for _, name := range names {
+		user, ok := configuration.NamedSectionGet(name, Remote, RemoteUser, "")
+		if !ok || user != want[name] {
+			t.Errorf("user of %q = %q (%v), want %q", name, user, ok, want[name])
+		}
+	}
+}
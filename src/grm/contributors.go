@@ -0,0 +1,203 @@
+package main
+
+import (
+	"github.com/google/go-github/github"
+	"grm/config"
+	"strings"
+	"sort"
+)
+
+// Bots are ignored unless the contributors-ignore property says otherwise
+const defaultContributorsIgnore = "*[bot]"
+
+// An author of commits in the range of a release. First-time contributors have no commits
+// before the previous release.
+type contributor struct {
+	login     string
+	name      string
+	email     string
+	firstTime bool
+}
+
+// Settings of a definition to select and merge the contributors of a release
+type contributorSettings struct {
+	ignore  []string
+	aliases map[string]string
+}
+
+// Reads the ignore list and alias map of a definition. Aliases map a login, git author name or
+// email address to the login to credit, e.g. "jdoe-work=jdoe,John Doe=jdoe".
+func readContributorSettings(name string) *contributorSettings {
+	ignore := defaultContributorsIgnore
+	if value, ok := configuration.NamedSectionGet(name, config.Remote, config.ContributorsIgnore, ""); ok {
+		ignore = value
+	}
+
+	settings := &contributorSettings{
+		ignore:  make([]string, 0),
+		aliases: make(map[string]string),
+	}
+	for _, pattern := range strings.Split(ignore, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			settings.ignore = append(settings.ignore, strings.ToLower(pattern))
+		}
+	}
+
+	if value, ok := configuration.NamedSectionGet(name, config.Remote, config.ContributorAliases, ""); ok {
+		for _, mapping := range strings.Split(value, ",") {
+			parts := strings.SplitN(mapping, "=", 2)
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+				continue
+			}
+			settings.aliases[strings.ToLower(strings.TrimSpace(parts[0]))] = strings.TrimSpace(parts[1])
+		}
+	}
+	return settings
+}
+
+// Ignore patterns match logins and names case-insensitive, a leading or trailing "*" matches any prefix or suffix
+func (s *contributorSettings) isIgnored(value string) bool {
+	if value == "" {
+		return false
+	}
+	value = strings.ToLower(value)
+
+	for _, pattern := range s.ignore {
+		prefix := strings.HasSuffix(pattern, "*")
+		suffix := strings.HasPrefix(pattern, "*")
+		trimmed := strings.Trim(pattern, "*")
+
+		switch {
+		case prefix && suffix && strings.Contains(value, trimmed):
+			return true
+		case prefix && !suffix && strings.HasPrefix(value, trimmed):
+			return true
+		case suffix && !prefix && strings.HasSuffix(value, trimmed):
+			return true
+		case value == pattern:
+			return true
+		}
+	}
+	return false
+}
+
+// Resolves the login to credit, aliases take precedence over the Github login of the commit
+func (s *contributorSettings) resolve(login, name, email string) string {
+	for _, key := range []string{login, name, email} {
+		if alias, ok := s.aliases[strings.ToLower(key)]; ok {
+			return alias
+		}
+	}
+	return login
+}
+
+// Collects the unique authors of the commits between the previous and the release tag, and the
+// authors of the pull requests merged in between, which may be squashed or merged by someone else.
+// Merge commits are skipped, since their author merged the pull request rather than contributing
+// to it. Releases without previous tag have no contributors.
+func readReleaseContributors(account, repository string, release *release, settings *contributorSettings,
+	fetcher repositoryFetcher) ([]*contributor, error) {
+
+	if release.previousTag == "" {
+		return nil, nil
+	}
+
+	comparison, err := readReleaseComparison(account, repository, release, fetcher)
+	if err != nil {
+		return nil, err
+	}
+
+	contributors := make([]*contributor, 0)
+	lookup := make(map[string]*contributor)
+	add := func(login, name, email string) {
+		if settings.isIgnored(login) || settings.isIgnored(name) {
+			return
+		}
+
+		login = settings.resolve(login, name, email)
+
+		// Commits of unknown users are merged by their email address
+		key := strings.ToLower(login)
+		if key == "" {
+			key = strings.ToLower(email)
+		}
+		if key == "" {
+			return
+		}
+
+		if _, ok := lookup[key]; !ok {
+			c := &contributor{login: login, name: name, email: email}
+			lookup[key] = c
+			contributors = append(contributors, c)
+		}
+	}
+
+	for _, commit := range comparison.Commits {
+		if len(commit.Parents) > 1 {
+			continue
+		}
+		author := commit.GetCommit().GetAuthor()
+		add(commit.GetAuthor().GetLogin(), author.GetName(), author.GetEmail())
+	}
+
+	for _, login := range pullRequestAuthors(account, release, comparison) {
+		add(login, "", "")
+	}
+
+	for _, c := range contributors {
+		author := c.login
+		if author == "" {
+			author = c.email
+		}
+		committed, err := fetcher.hasCommitsBefore(account, repository, release.previousTag, author)
+		if err != nil {
+			return nil, err
+		}
+		c.firstTime = !committed
+	}
+
+	sort.Slice(contributors, func(i, j int) bool {
+		return strings.ToLower(contributorName(contributors[i])) < strings.ToLower(contributorName(contributors[j]))
+	})
+	return contributors, nil
+}
+
+// The authors of the pull requests of a release, taken from the milestone changelog and the
+// merge commits since the previous tag. Squashed pull requests are credited by their commit.
+// Merge commits name the owner of the merged branch, which is the repository account itself
+// for branches not coming from a fork.
+func pullRequestAuthors(account string, release *release, comparison *github.CommitsComparison) []string {
+	authors := make([]string, 0)
+	if release.changelog != nil {
+		for _, entry := range release.changelog.pullRequests {
+			authors = append(authors, entry.author)
+		}
+	}
+
+	for _, commit := range comparison.Commits {
+		match := mergeCommitPattern.FindStringSubmatch(commit.GetCommit().GetMessage())
+		if match != nil && !strings.EqualFold(match[2], account) {
+			authors = append(authors, match[2])
+		}
+	}
+	return authors
+}
+
+// Reads the commits between the previous and the release tag once, for both changelog and contributors
+func readReleaseComparison(account, repository string, release *release, fetcher repositoryFetcher) (*github.CommitsComparison, error) {
+	if release.comparison == nil {
+		comparison, err := fetcher.readComparison(account, repository, release.previousTag, release.tag)
+		if err != nil {
+			return nil, err
+		}
+		release.comparison = comparison
+	}
+	return release.comparison, nil
+}
+
+func contributorName(c *contributor) string {
+	if c.login != "" {
+		return c.login
+	}
+	return c.name
+}
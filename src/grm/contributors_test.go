@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"github.com/google/go-github/github"
+	"reflect"
+)
+
+func TestContributorSettingsIgnore(t *testing.T) {
+	settings := &contributorSettings{ignore: []string{"*[bot]", "release-*", "*robot*", "jdoe"}}
+
+	tests := []struct {
+		value   string
+		ignored bool
+	}{
+		{"dependabot[bot]", true},
+		{"Renovate[BOT]", true},
+		{"release-manager", true},
+		{"the-robot-account", true},
+		{"JDoe", true},
+		{"jdoe2", false},
+		{"octocat", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if ignored := settings.isIgnored(test.value); ignored != test.ignored {
+			t.Errorf("%q: ignored = %v, want %v", test.value, ignored, test.ignored)
+		}
+	}
+}
+
+func TestReadReleaseContributors(t *testing.T) {
+	merge := testCommit("m1", "maintainer", "Maintainer", "Merge pull request #12 from octocat/feature\n\nAdd feature")
+	merge.Parents = []github.Commit{{SHA: github.String("p1")}, {SHA: github.String("p2")}}
+	branch := testCommit("m2", "maintainer", "Maintainer", "Merge pull request #13 from noctarius/branch")
+	branch.Parents = []github.Commit{{SHA: github.String("p3")}, {SHA: github.String("p4")}}
+
+	fetcher := &fakeFetcher{
+		comparison: &github.CommitsComparison{Commits: []github.RepositoryCommit{
+			testCommit("a1", "jdoe", "John Doe", "Fix parsing (#14)"),
+			testCommit("a2", "jdoe-work", "John Doe", "Update docs"),
+			testCommit("a3", "", "Jane Roe", "Add example"),
+			testCommit("a4", "dependabot[bot]", "dependabot[bot]", "Bump dependency"),
+			merge,
+			branch,
+		}},
+		previousAuthors: map[string]bool{"jdoe": true},
+	}
+	settings := &contributorSettings{
+		ignore:  []string{"*[bot]"},
+		aliases: map[string]string{"jdoe-work": "jdoe"},
+	}
+
+	tests := []struct {
+		name      string
+		changelog *changelog
+		want      []contributor
+	}{
+		{
+			name: "commit and merged pull request authors",
+			want: []contributor{
+				{name: "Jane Roe", email: "jane roe@example.com", firstTime: true},
+				{login: "jdoe", name: "John Doe", email: "john doe@example.com"},
+				{login: "octocat", firstTime: true},
+			},
+		},
+		{
+			name: "milestone pull requests",
+			changelog: &changelog{pullRequests: []*changelogEntry{
+				{number: 11, pullRequest: true, author: "hubot"},
+				{number: 12, pullRequest: true, author: "octocat"},
+			}},
+			want: []contributor{
+				{login: "hubot", firstTime: true},
+				{name: "Jane Roe", email: "jane roe@example.com", firstTime: true},
+				{login: "jdoe", name: "John Doe", email: "john doe@example.com"},
+				{login: "octocat", firstTime: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		rel := &release{tag: "v1.1.0", previousTag: "v1.0.0", changelog: test.changelog}
+		contributors, err := readReleaseContributors("noctarius", "example", rel, settings, fetcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := make([]contributor, 0, len(contributors))
+		for _, c := range contributors {
+			got = append(got, *c)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: contributors = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestReadReleaseContributorsWithoutPreviousTag(t *testing.T) {
+	contributors, err := readReleaseContributors("noctarius", "example", &release{tag: "v1.0.0"},
+		&contributorSettings{}, &fakeFetcher{})
+	if err != nil || contributors != nil {
+		t.Errorf("contributors = %v, %v, want none", contributors, err)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"time"
+	"regexp"
+	"strconv"
+	"strings"
+	"fmt"
+	"github.com/araddon/dateparse"
+	"grm/config"
+)
+
+var (
+	relativeDatePattern = regexp.MustCompile(`^(\d+)([hdwmy])$`)
+	quarterPattern      = regexp.MustCompile(`^(\d{4})-[qQ]([1-4])$`)
+	monthPattern        = regexp.MustCompile(`^(\d{4})-(\d{2})$`)
+	yearPattern         = regexp.MustCompile(`^(\d{4})$`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Closed interval of release dates, a zero until leaves the interval open ended
+type dateRange struct {
+	since time.Time
+	until time.Time
+}
+
+func (r dateRange) contains(date time.Time) bool {
+	if date.Before(r.since) {
+		return false
+	}
+	return r.until.IsZero() || !date.After(r.until)
+}
+
+// Builds the date range of a report from either a period or the since and until expressions
+func parseDateRange(since, until, period string, now time.Time, location *time.Location) (dateRange, error) {
+	if period != "" {
+		if since != "" || until != "" {
+			return dateRange{}, fmt.Errorf("--period cannot be combined with --since or --until")
+		}
+		return parsePeriod(period, now, location)
+	}
+
+	r := dateRange{since: time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if since != "" {
+		date, _, err := parseDateExpression(since, now, location)
+		if err != nil {
+			return dateRange{}, fmt.Errorf("could not parse since date: %v", err)
+		}
+		r.since = date
+	}
+
+	if until != "" {
+		date, day, err := parseDateExpression(until, now, location)
+		if err != nil {
+			return dateRange{}, fmt.Errorf("could not parse until date: %v", err)
+		}
+		// A day includes all releases up to its end
+		if day {
+			date = date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		r.until = date
+	}
+
+	if !r.until.IsZero() && r.until.Before(r.since) {
+		return dateRange{}, fmt.Errorf("until date %s is before since date %s",
+			r.until.Format(time.RFC3339), r.since.Format(time.RFC3339))
+	}
+	return r, nil
+}
+
+// Parses absolute dates and relative expressions like 2w, yesterday or last-monday. The
+// returned flag tells if the expression denotes a whole day rather than a point in time.
+func parseDateExpression(expression string, now time.Time, location *time.Location) (time.Time, bool, error) {
+	now = now.In(location)
+	today := startOfDay(now)
+	expression = strings.TrimSpace(expression)
+
+	// Keywords are case insensitive, absolute dates are parsed as given, e.g. 2018-05-01T10:00:00Z
+	keyword := strings.ToLower(expression)
+	switch keyword {
+	case "now":
+		return now, false, nil
+	case "today":
+		return today, true, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true, nil
+	}
+
+	if strings.HasPrefix(keyword, "last-") {
+		weekday, ok := weekdays[strings.TrimPrefix(keyword, "last-")]
+		if !ok {
+			return time.Time{}, false, fmt.Errorf("unknown weekday: %s", expression)
+		}
+		date := today.AddDate(0, 0, -1)
+		for date.Weekday() != weekday {
+			date = date.AddDate(0, 0, -1)
+		}
+		return date, true, nil
+	}
+
+	if match := relativeDatePattern.FindStringSubmatch(keyword); match != nil {
+		amount, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "h":
+			return now.Add(-time.Duration(amount) * time.Hour), false, nil
+		case "d":
+			return today.AddDate(0, 0, -amount), true, nil
+		case "w":
+			return today.AddDate(0, 0, -7*amount), true, nil
+		case "m":
+			return today.AddDate(0, -amount, 0), true, nil
+		case "y":
+			return today.AddDate(-amount, 0, 0), true, nil
+		}
+	}
+
+	date, err := dateparse.ParseIn(expression, location)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return date, date.Equal(startOfDay(date)), nil
+}
+
+// Parses periods like last-month, this-quarter, 2018-Q2, 2018-05 or 2018 into a closed date range
+func parsePeriod(period string, now time.Time, location *time.Location) (dateRange, error) {
+	now = now.In(location)
+	period = strings.ToLower(strings.TrimSpace(period))
+
+	var start, end time.Time
+	switch {
+	case strings.HasPrefix(period, "this-") || strings.HasPrefix(period, "last-"):
+		offset := 0
+		if strings.HasPrefix(period, "last-") {
+			offset = -1
+		}
+
+		switch period[5:] {
+		case "week":
+			// Weeks start on monday
+			start = startOfDay(now).AddDate(0, 0, -((int(now.Weekday())+6)%7)+7*offset)
+			end = start.AddDate(0, 0, 7)
+		case "month":
+			start = time.Date(now.Year(), now.Month()+time.Month(offset), 1, 0, 0, 0, 0, location)
+			end = start.AddDate(0, 1, 0)
+		case "quarter":
+			quarter := (int(now.Month()) - 1) / 3
+			start = time.Date(now.Year(), time.Month((quarter+offset)*3+1), 1, 0, 0, 0, 0, location)
+			end = start.AddDate(0, 3, 0)
+		case "year":
+			start = time.Date(now.Year()+offset, 1, 1, 0, 0, 0, 0, location)
+			end = start.AddDate(1, 0, 0)
+		default:
+			return dateRange{}, fmt.Errorf("unknown period: %s", period)
+		}
+
+	case quarterPattern.MatchString(period):
+		match := quarterPattern.FindStringSubmatch(period)
+		year, _ := strconv.Atoi(match[1])
+		quarter, _ := strconv.Atoi(match[2])
+		start = time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, location)
+		end = start.AddDate(0, 3, 0)
+
+	case monthPattern.MatchString(period):
+		match := monthPattern.FindStringSubmatch(period)
+		year, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		if month < 1 || month > 12 {
+			return dateRange{}, fmt.Errorf("unknown period: %s", period)
+		}
+		start = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, location)
+		end = start.AddDate(0, 1, 0)
+
+	case yearPattern.MatchString(period):
+		year, _ := strconv.Atoi(period)
+		start = time.Date(year, 1, 1, 0, 0, 0, 0, location)
+		end = start.AddDate(1, 0, 0)
+
+	default:
+		return dateRange{}, fmt.Errorf("unknown period: %s", period)
+	}
+
+	return dateRange{since: start, until: end.Add(-time.Nanosecond)}, nil
+}
+
+// Reads the timezone used to interpret dates, the command line takes precedence over the
+// timezone property of the remote definition, default: UTC
+func readLocation(name, timezone string) (*time.Location, error) {
+	if timezone == "" {
+		if t, ok := configuration.NamedSectionGet(name, config.Remote, config.Timezone, ""); ok {
+			timezone = t
+		}
+	}
+	if timezone == "" {
+		return time.UTC, nil
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone '%s': %v", timezone, err)
+	}
+	return location, nil
+}
+
+func startOfDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Wednesday, May 16th 2018
+var testNow = time.Date(2018, 5, 16, 15, 30, 0, 0, time.UTC)
+
+func TestParseDateExpression(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+
+	tests := []struct {
+		expression string
+		location   *time.Location
+		want       time.Time
+		day        bool
+		err        bool
+	}{
+		{"now", time.UTC, testNow, false, false},
+		{"today", time.UTC, time.Date(2018, 5, 16, 0, 0, 0, 0, time.UTC), true, false},
+		{" Yesterday ", time.UTC, time.Date(2018, 5, 15, 0, 0, 0, 0, time.UTC), true, false},
+		{"last-monday", time.UTC, time.Date(2018, 5, 14, 0, 0, 0, 0, time.UTC), true, false},
+		{"LAST-WEDNESDAY", time.UTC, time.Date(2018, 5, 9, 0, 0, 0, 0, time.UTC), true, false},
+		{"last-someday", time.UTC, time.Time{}, false, true},
+		{"5h", time.UTC, time.Date(2018, 5, 16, 10, 30, 0, 0, time.UTC), false, false},
+		{"3d", time.UTC, time.Date(2018, 5, 13, 0, 0, 0, 0, time.UTC), true, false},
+		{"2W", time.UTC, time.Date(2018, 5, 2, 0, 0, 0, 0, time.UTC), true, false},
+		{"1m", time.UTC, time.Date(2018, 4, 16, 0, 0, 0, 0, time.UTC), true, false},
+		{"1y", time.UTC, time.Date(2017, 5, 16, 0, 0, 0, 0, time.UTC), true, false},
+		{"2018-05-01", time.UTC, time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC), true, false},
+		{"2018-05-01T10:00:00Z", time.UTC, time.Date(2018, 5, 1, 10, 0, 0, 0, time.UTC), false, false},
+		{"2018-05-01T10:00:00+02:00", time.UTC, time.Date(2018, 5, 1, 8, 0, 0, 0, time.UTC), false, false},
+		{"today", berlin, time.Date(2018, 5, 16, 0, 0, 0, 0, berlin), true, false},
+		{"2018-05-01", berlin, time.Date(2018, 5, 1, 0, 0, 0, 0, berlin), true, false},
+		{"not a date", time.UTC, time.Time{}, false, true},
+	}
+
+	for _, test := range tests {
+		got, day, err := parseDateExpression(test.expression, testNow, test.location)
+		if (err != nil) != test.err {
+			t.Errorf("%q: error = %v, want error %v", test.expression, err, test.err)
+			continue
+		}
+		if test.err {
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("%q: date = %s, want %s", test.expression, got, test.want)
+		}
+		if day != test.day {
+			t.Errorf("%q: day = %v, want %v", test.expression, day, test.day)
+		}
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		period string
+		since  time.Time
+		until  time.Time
+		err    bool
+	}{
+		{"this-week", time.Date(2018, 5, 14, 0, 0, 0, 0, time.UTC), time.Date(2018, 5, 21, 0, 0, 0, 0, time.UTC), false},
+		{"last-week", time.Date(2018, 5, 7, 0, 0, 0, 0, time.UTC), time.Date(2018, 5, 14, 0, 0, 0, 0, time.UTC), false},
+		{"this-month", time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC), false},
+		{"Last-Month", time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC), false},
+		{"this-quarter", time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC), false},
+		{"last-quarter", time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC), false},
+		{"last-year", time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2018-Q2", time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2017-q4", time.Date(2017, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2018-02", time.Date(2018, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2018", time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2018-13", time.Time{}, time.Time{}, true},
+		{"this-decade", time.Time{}, time.Time{}, true},
+		{"2018-Q5", time.Time{}, time.Time{}, true},
+	}
+
+	for _, test := range tests {
+		r, err := parsePeriod(test.period, testNow, time.UTC)
+		if (err != nil) != test.err {
+			t.Errorf("%q: error = %v, want error %v", test.period, err, test.err)
+			continue
+		}
+		if test.err {
+			continue
+		}
+		// Periods are closed intervals ending right before the next period starts
+		if !r.since.Equal(test.since) || !r.until.Equal(test.until.Add(-time.Nanosecond)) {
+			t.Errorf("%q: range = %s - %s, want %s - %s", test.period, r.since, r.until, test.since, test.until)
+		}
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	tests := []struct {
+		name                 string
+		since, until, period string
+		wantSince, wantUntil time.Time
+		err                  bool
+	}{
+		{"open ended", "2018-05-01", "", "", time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC), time.Time{}, false},
+		{"until includes the whole day", "2018-05-01", "2018-05-10", "",
+			time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 5, 11, 0, 0, 0, -1, time.UTC), false},
+		{"until a point in time", "", "2018-05-10T12:00:00Z", "",
+			time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 5, 10, 12, 0, 0, 0, time.UTC), false},
+		{"period", "", "", "2018-05",
+			time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 6, 1, 0, 0, 0, -1, time.UTC), false},
+		{"period and since", "2018-05-01", "", "2018-05", time.Time{}, time.Time{}, true},
+		{"until before since", "2018-05-10", "2018-05-01", "", time.Time{}, time.Time{}, true},
+		{"invalid since", "someday", "", "", time.Time{}, time.Time{}, true},
+	}
+
+	for _, test := range tests {
+		r, err := parseDateRange(test.since, test.until, test.period, testNow, time.UTC)
+		if (err != nil) != test.err {
+			t.Errorf("%s: error = %v, want error %v", test.name, err, test.err)
+			continue
+		}
+		if test.err {
+			continue
+		}
+		if !r.since.Equal(test.wantSince) || !r.until.Equal(test.wantUntil) {
+			t.Errorf("%s: range = %s - %s, want %s - %s", test.name, r.since, r.until, test.wantSince, test.wantUntil)
+		}
+	}
+}
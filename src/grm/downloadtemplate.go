@@ -0,0 +1,133 @@
+package main
+
+import (
+	"grm/version"
+	"fmt"
+	"strings"
+	"strconv"
+	"time"
+)
+
+// Placeholders of download url templates, {name} is kept for existing templates
+var templatePlaceholders = map[string]func(target *downloadTarget) string{
+	"account":    func(target *downloadTarget) string { return target.account },
+	"owner":      func(target *downloadTarget) string { return target.account },
+	"name":       func(target *downloadTarget) string { return target.account },
+	"repository": func(target *downloadTarget) string { return target.repository },
+	"version":    func(target *downloadTarget) string { return target.version },
+	"tag":        func(target *downloadTarget) string { return target.tag },
+	"milestone":  func(target *downloadTarget) string { return target.milestone },
+	"major":      func(target *downloadTarget) string { return versionNumber(target, (*version.Version).Major) },
+	"minor":      func(target *downloadTarget) string { return versionNumber(target, (*version.Version).Minor) },
+	"patch":      func(target *downloadTarget) string { return versionNumber(target, (*version.Version).Patch) },
+	"date":       func(target *downloadTarget) string { return formatTemplateDate(target.date) },
+}
+
+var templateTransforms = map[string]func(value string) string{
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"strip-v": registryVersion,
+	// Maven group ids to repository paths, e.g. com.example to com/example
+	"path": func(value string) string { return strings.Replace(value, ".", "/", -1) },
+}
+
+// A parsed download url template, e.g. https://example.com/{"com.example"|path}/{repository}/{tag|strip-v}
+type downloadTemplate struct {
+	segments []*templateSegment
+}
+
+// Either text or an expression, expressions start with a placeholder or a quoted literal
+// value, followed by the transforms to apply in order
+type templateSegment struct {
+	text        string
+	placeholder string
+	transforms  []string
+}
+
+func parseDownloadTemplate(template string) (*downloadTemplate, error) {
+	t := &downloadTemplate{segments: make([]*templateSegment, 0)}
+
+	remainder := template
+	for remainder != "" {
+		start := strings.Index(remainder, "{")
+		if start == -1 {
+			t.segments = append(t.segments, &templateSegment{text: remainder})
+			break
+		}
+		if start > 0 {
+			t.segments = append(t.segments, &templateSegment{text: remainder[:start]})
+		}
+
+		end := strings.Index(remainder[start:], "}")
+		if end == -1 {
+			return nil, fmt.Errorf("unclosed placeholder in download url template: %s", template)
+		}
+
+		segment, err := parseTemplateExpression(remainder[start+1 : start+end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid download url template %s: %v", template, err)
+		}
+		t.segments = append(t.segments, segment)
+		remainder = remainder[start+end+1:]
+	}
+	return t, nil
+}
+
+func parseTemplateExpression(expression string) (*templateSegment, error) {
+	parts := strings.Split(expression, "|")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	segment := &templateSegment{transforms: parts[1:]}
+	if value, err := strconv.Unquote(parts[0]); err == nil {
+		segment.text = value
+	} else if _, ok := templatePlaceholders[parts[0]]; ok {
+		segment.placeholder = parts[0]
+	} else {
+		return nil, fmt.Errorf("unknown placeholder {%s}", parts[0])
+	}
+
+	for _, transform := range segment.transforms {
+		if _, ok := templateTransforms[transform]; !ok {
+			return nil, fmt.Errorf("unknown transform %s", transform)
+		}
+	}
+	return segment, nil
+}
+
+func (t *downloadTemplate) render(target *downloadTarget) string {
+	result := ""
+	for _, segment := range t.segments {
+		value := segment.text
+		if segment.placeholder != "" {
+			value = templatePlaceholders[segment.placeholder](target)
+		}
+
+		for _, transform := range segment.transforms {
+			value = templateTransforms[transform](value)
+		}
+		result += value
+	}
+	return result
+}
+
+// Version numbers are taken from the parsed tag, falling back to the extracted version
+func versionNumber(target *downloadTarget, number func(v *version.Version) int) string {
+	v := target.parsedVersion
+	if v == nil {
+		parsed, err := version.Parse(target.version)
+		if err != nil {
+			return ""
+		}
+		v = parsed
+	}
+	return strconv.Itoa(number(v))
+}
+
+func formatTemplateDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+	"grm/version"
+)
+
+func TestDownloadTemplateRender(t *testing.T) {
+	parsed, _ := version.Parse("v2.3.4")
+	target := &downloadTarget{
+		account:       "Noctarius",
+		repository:    "example",
+		version:       "2.3.4",
+		tag:           "v2.3.4",
+		milestone:     "2.3.4",
+		date:          time.Date(2018, 5, 29, 12, 0, 0, 0, time.UTC),
+		parsedVersion: parsed,
+	}
+
+	tests := []struct {
+		template string
+		target   *downloadTarget
+		want     string
+	}{
+		{"https://example.com/{name}/{repository}/{version}", target, "https://example.com/Noctarius/example/2.3.4"},
+		{"https://example.com/{owner|lower}/{repository|upper}", target, "https://example.com/noctarius/EXAMPLE"},
+		{"https://example.com/{tag|strip-v}/{tag}", target, "https://example.com/2.3.4/v2.3.4"},
+		{"https://example.com/{major}.{minor}/{major}.{minor}.{patch}", target, "https://example.com/2.3/2.3.4"},
+		{"https://example.com/{ major | lower }", target, "https://example.com/2"},
+		{"https://repo.example.com/{\"com.example.tools\"|path}/{repository}", target,
+			"https://repo.example.com/com/example/tools/example"},
+		{"https://example.com/{date}/{milestone}", target, "https://example.com/2018-05-29/2.3.4"},
+		{"https://example.com/static", target, "https://example.com/static"},
+		// Without parsed tag, version numbers come from the extracted version
+		{"https://example.com/{major}/{minor}", &downloadTarget{version: "1.7"}, "https://example.com/1/7"},
+		{"https://example.com/{major}/{date}", &downloadTarget{version: "snapshot"}, "https://example.com//"},
+	}
+
+	for _, test := range tests {
+		template, err := parseDownloadTemplate(test.template)
+		if err != nil {
+			t.Errorf("%s: %v", test.template, err)
+			continue
+		}
+		if got := template.render(test.target); got != test.want {
+			t.Errorf("%s: url = %s, want %s", test.template, got, test.want)
+		}
+	}
+}
+
+func TestParseDownloadTemplateInvalid(t *testing.T) {
+	for _, template := range []string{
+		"https://example.com/{unknown}",
+		"https://example.com/{version|reverse}",
+		"https://example.com/{version",
+		"https://example.com/{\"unterminated|path}",
+		"https://example.com/{}",
+	} {
+		if _, err := parseDownloadTemplate(template); err == nil {
+			t.Errorf("%s: parsed, want error", template)
+		}
+	}
+}
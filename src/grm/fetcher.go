@@ -0,0 +1,349 @@
+package main
+
+import (
+	"github.com/google/go-github/github"
+	"grm/config"
+	"time"
+	"fmt"
+	"context"
+	"net/http"
+	"strings"
+	"strconv"
+	"io"
+	"io/ioutil"
+	"sort"
+	"grm/version"
+	"net/url"
+)
+
+// Only small assets like checksum files are read, larger content is cut off
+const maxAssetContent = 1024 * 1024
+
+// A tag of a repository, the commit and tagger dates are only known if the fetcher retrieves them
+// alongside the tag. Annotated tags reference a tag object, lightweight tags don't.
+type tagRef struct {
+	name      string
+	sha       string
+	object    string
+	committed time.Time
+	tagged    time.Time
+}
+
+// Orders tags by version descending, tags which aren't a version follow by commit date, newest
+// first. The previous tag of a release is the next tag in this order, so all fetchers have to
+// return the same order.
+func sortTagRefs(tags []*tagRef) {
+	versions := make(map[string]*version.Version, len(tags))
+	for _, tag := range tags {
+		if v, err := version.Parse(tag.name); err == nil {
+			versions[tag.name] = v
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		a, b := versions[tags[i].name], versions[tags[j].name]
+		switch {
+		case a != nil && b != nil:
+			if c := version.Compare(a, b); c != 0 {
+				return c > 0
+			}
+			return tags[i].name > tags[j].name
+		case a != nil:
+			return true
+		case b != nil:
+			return false
+		case !tags[i].committed.Equal(tags[j].committed):
+			return tags[i].committed.After(tags[j].committed)
+		}
+		return tags[i].name > tags[j].name
+	})
+}
+
+// Retrieves the milestones, tags and releases of repositories from the Github API
+type repositoryFetcher interface {
+	readMilestones(account, repository string) ([]*github.Milestone, error)
+	readTags(name, account, repository string) ([]*tagRef, error)
+	readReleases(name, account, repository string) ([]*github.RepositoryRelease, error)
+	readCommitDate(account, repository string, tag *tagRef) (time.Time, error)
+	readTagDate(account, repository string, tag *tagRef) (time.Time, error)
+	readMilestoneIssues(account, repository string, milestone int) ([]*github.Issue, error)
+	readComparison(account, repository, base, head string) (*github.CommitsComparison, error)
+	hasCommitsBefore(account, repository, ref, author string) (bool, error)
+	readReleaseAsset(account, repository string, id int64) ([]byte, error)
+	readReleaseByTag(account, repository, tag string) (*github.RepositoryRelease, error)
+}
+
+// Creates the fetcher selected by the api-mode of the remote definition
+func newRepositoryFetcher(name string, repositories []*github.Repository, client *github.Client) (repositoryFetcher, error) {
+	apiMode := "rest"
+	if m, ok := configuration.NamedSectionGet(name, config.Remote, config.ApiMode, ""); ok && m != "" {
+		apiMode = m
+	}
+
+	switch apiMode {
+	case "rest":
+		fetcher, err := newRestFetcher(name, client)
+		if err != nil {
+			return nil, err
+		}
+		return fetcher, nil
+	case "graphql":
+		fetcher, err := newGraphqlFetcher(name, repositories, client)
+		if err != nil {
+			return nil, err
+		}
+		return fetcher, nil
+	}
+
+	return nil, fmt.Errorf("unknown api mode: %s", apiMode)
+}
+
+// Uses one REST call per page of milestones, tags and releases, and one per tagged commit
+type restFetcher struct {
+	client *github.Client
+	// Release assets are redirected to pre-signed storage urls, which reject Github credentials
+	downloadClient *http.Client
+}
+
+func newRestFetcher(name string, client *github.Client) (*restFetcher, error) {
+	transport, err := newBaseTransport(name)
+	if err != nil {
+		return nil, err
+	}
+	return &restFetcher{
+		client:         client,
+		downloadClient: &http.Client{Transport: transport, Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (f *restFetcher) readMilestones(account, repository string) ([]*github.Milestone, error) {
+	return readMilestones(account, repository, f.client)
+}
+
+func (f *restFetcher) readTags(name, account, repository string) ([]*tagRef, error) {
+	tags, err := readTags(name, account, repository, f.client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tag objects are only listed when needed, since tags don't tell if they are annotated
+	var objects map[string]string = nil
+	dateSource, err := readReleaseDateSource(name, repository)
+	if err != nil {
+		return nil, err
+	}
+	if dateSource == "tag" || dateSource == "auto" {
+		objects, err = readTagObjects(account, repository, f.client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	refs := make([]*tagRef, 0, len(tags))
+	for _, tag := range tags {
+		refs = append(refs, &tagRef{
+			name:   tag.GetName(),
+			sha:    tag.GetCommit().GetSHA(),
+			object: objects[tag.GetName()],
+		})
+	}
+	sortTagRefs(refs)
+	return refs, nil
+}
+
+func (f *restFetcher) readReleases(name, account, repository string) ([]*github.RepositoryRelease, error) {
+	return readReleases(name, account, repository, f.client)
+}
+
+func (f *restFetcher) readCommitDate(account, repository string, tag *tagRef) (time.Time, error) {
+	commit, err := readCommit(account, repository, tag.sha, f.client)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return commit.GetCommit().GetCommitter().GetDate(), nil
+}
+
+// Lightweight tags have no tagger date, the returned date is zero
+func (f *restFetcher) readTagDate(account, repository string, tag *tagRef) (time.Time, error) {
+	if tag.object == "" {
+		return time.Time{}, nil
+	}
+
+	ctx := context.Background()
+
+	t, _, err := f.client.Git.GetTag(ctx, account, repository, tag.object)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not retrieve tag object %s: %v", tag.object, err)
+	}
+	return t.GetTagger().GetDate(), nil
+}
+
+func (f *restFetcher) readMilestoneIssues(account, repository string, milestone int) ([]*github.Issue, error) {
+	return readMilestoneIssues(account, repository, milestone, f.client)
+}
+
+// Without pagination Github lists at most 250 commits of a comparison, the commits of all pages are collected
+func (f *restFetcher) readComparison(account, repository, base, head string) (*github.CommitsComparison, error) {
+	ctx := context.Background()
+
+	var comparison *github.CommitsComparison = nil
+	page := 1
+	for {
+		u := fmt.Sprintf("repos/%v/%v/compare/%v...%v?per_page=100&page=%d", account, repository,
+			url.PathEscape(base), url.PathEscape(head), page)
+		req, err := f.client.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		c := new(github.CommitsComparison)
+		response, err := f.client.Do(ctx, req, c)
+		if err != nil {
+			return nil, fmt.Errorf("could not compare %s...%s: %v", base, head, err)
+		}
+
+		if comparison == nil {
+			comparison = c
+		} else {
+			comparison.Commits = append(comparison.Commits, c.Commits...)
+		}
+
+		if hasMorePages(response) {
+			page++
+			continue
+		}
+		return comparison, nil
+	}
+}
+
+// Tells if the author (a Github login or email address) committed to the history of ref
+func (f *restFetcher) hasCommitsBefore(account, repository, ref, author string) (bool, error) {
+	ctx := context.Background()
+
+	options := &github.CommitsListOptions{
+		SHA:         ref,
+		Author:      author,
+		ListOptions: github.ListOptions{PerPage: 1},
+	}
+	commits, _, err := f.client.Repositories.ListCommits(ctx, account, repository, options)
+	if err != nil {
+		return false, fmt.Errorf("could not list commits of %s before %s: %v", author, ref, err)
+	}
+	return len(commits) > 0, nil
+}
+
+// Reads the Github Release of a tag, tags without release have none
+func (f *restFetcher) readReleaseByTag(account, repository, tag string) (*github.RepositoryRelease, error) {
+	ctx := context.Background()
+
+	release, response, err := f.client.Repositories.GetReleaseByTag(ctx, account, repository, tag)
+	if err != nil {
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not retrieve release of tag %s: %v", tag, err)
+	}
+	return release, nil
+}
+
+// Reads the content of a (small) release asset, e.g. a checksum file
+func (f *restFetcher) readReleaseAsset(account, repository string, id int64) ([]byte, error) {
+	ctx := context.Background()
+
+	content, redirectUrl, err := f.client.Repositories.DownloadReleaseAsset(ctx, account, repository, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not download release asset %d: %v", id, err)
+	}
+
+	if redirectUrl != "" {
+		response, err := f.downloadClient.Get(redirectUrl)
+		if err != nil {
+			return nil, fmt.Errorf("could not download release asset %d: %v", id, err)
+		}
+		if response.StatusCode != http.StatusOK {
+			response.Body.Close()
+			return nil, fmt.Errorf("could not download release asset %d: %s", id, response.Status)
+		}
+		content = response.Body
+	}
+	defer content.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(content, maxAssetContent))
+	if err != nil {
+		return nil, fmt.Errorf("could not download release asset %d: %v", id, err)
+	}
+	return data, nil
+}
+
+// Reads the closed issues and pull requests of a milestone, oldest first
+func readMilestoneIssues(account, repository string, milestone int, client *github.Client) ([]*github.Issue, error) {
+	ctx := context.Background()
+
+	issues := make([]*github.Issue, 0)
+
+	page := 1
+	for {
+		i, response, err := client.Issues.ListByRepo(ctx, account, repository, &github.IssueListByRepoOptions{
+			Milestone: strconv.Itoa(milestone),
+			State:     "closed",
+			Sort:      "created",
+			Direction: "asc",
+			ListOptions: github.ListOptions{
+				PerPage: 100,
+				Page:    page,
+			},
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("could not retrieve issues of milestone %d: %v", milestone, err)
+		}
+
+		issues = append(issues, i...)
+
+		if hasMorePages(response) {
+			page++
+			continue
+		}
+
+		return issues, nil
+	}
+}
+
+// Reads the tag object shas of all annotated tags of a repository
+func readTagObjects(account, repository string, client *github.Client) (map[string]string, error) {
+	ctx := context.Background()
+
+	objects := make(map[string]string)
+
+	page := 1
+	for {
+		refs, response, err := client.Git.ListRefs(ctx, account, repository, &github.ReferenceListOptions{
+			Type: "tags",
+			ListOptions: github.ListOptions{
+				PerPage: 100,
+				Page:    page,
+			},
+		})
+
+		if err != nil {
+			// Repositories without any tag answer with 404
+			if response != nil && response.StatusCode == http.StatusNotFound {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("could not retrieve tag references: %v", err)
+		}
+
+		for _, ref := range refs {
+			if ref.GetObject().GetType() == "tag" {
+				objects[strings.TrimPrefix(ref.GetRef(), "refs/tags/")] = ref.GetObject().GetSHA()
+			}
+		}
+
+		if hasMorePages(response) {
+			page++
+			continue
+		}
+
+		return objects, nil
+	}
+}
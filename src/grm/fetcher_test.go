@@ -0,0 +1,257 @@
+package main
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"github.com/google/go-github/github"
+	"fmt"
+	"strings"
+	"reflect"
+	"time"
+	"strconv"
+)
+
+func TestFetchersOrderTagsAlike(t *testing.T) {
+	// Both apis return the tags in a different order than the fetchers do
+	restTags := `[{"name":"v1.1.0","commit":{"sha":"c2"}},{"name":"v1.0.0","commit":{"sha":"c1"}},` +
+		`{"name":"v1.2.0","commit":{"sha":"c3"}},{"name":"v1.10.0","commit":{"sha":"c4"}}]`
+	graphqlRefs := `{"data":{"r0":{"refs":{"pageInfo":{"hasNextPage":false},"nodes":[` +
+		`{"name":"v1.10.0","target":{"__typename":"Commit","oid":"c4","committedDate":"2018-04-01T00:00:00Z"}},` +
+		`{"name":"v1.0.0","target":{"__typename":"Commit","oid":"c1","committedDate":"2018-01-01T00:00:00Z"}},` +
+		`{"name":"v1.2.0","target":{"__typename":"Tag","oid":"t3","tagger":{"date":"2018-03-01T00:00:00Z"},` +
+		`"target":{"__typename":"Commit","oid":"c3","committedDate":"2018-03-01T00:00:00Z"}}},` +
+		`{"name":"v1.1.0","target":{"__typename":"Commit","oid":"c2","committedDate":"2018-02-01T00:00:00Z"}}]},` +
+		`"milestones":{"pageInfo":{"hasNextPage":false},"nodes":[]}}}}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		switch request.URL.Path {
+		case "/api/v3/repos/noctarius/example/tags":
+			writer.Write([]byte(restTags))
+		case "/api/graphql":
+			writer.Write([]byte(graphqlRefs))
+		default:
+			http.NotFound(writer, request)
+		}
+	}))
+	defer server.Close()
+
+	defer setupTestConfiguration(t, fmt.Sprintf("[Remote \"foo\"]\napi-url=%s/api/v3/\n", server.URL))()
+
+	client, err := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repositories := []*github.Repository{{
+		Name:     github.String("example"),
+		FullName: github.String("noctarius/example"),
+		Owner:    &github.User{Login: github.String("noctarius")},
+	}}
+
+	want := []string{"v1.10.0", "v1.2.0", "v1.1.0", "v1.0.0"}
+	rest, err := newRestFetcher("foo", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graphql, err := newGraphqlFetcher("foo", repositories, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetchers := map[string]repositoryFetcher{
+		"rest":    rest,
+		"graphql": graphql,
+	}
+	for mode, fetcher := range fetchers {
+		tags, err := fetcher.readTags("foo", "noctarius", "example")
+		if err != nil {
+			t.Errorf("%s: %v", mode, err)
+			continue
+		}
+		names := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			names = append(names, tag.name)
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("%s: tags = %s, want %s", mode, strings.Join(names, " "), strings.Join(want, " "))
+		}
+	}
+}
+
+func TestSortTagRefs(t *testing.T) {
+	date := func(day int) time.Time { return time.Date(2018, 5, day, 0, 0, 0, 0, time.UTC) }
+	tags := []*tagRef{
+		{name: "v1.2.0"}, {name: "nightly", committed: date(1)}, {name: "v1.10.0"}, {name: "v1.1.0"},
+		{name: "v1.10.0-rc.1"}, {name: "latest", committed: date(3)}, {name: "v1.9.0"}, {name: "v2.0.0"},
+	}
+	sortTagRefs(tags)
+
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.name)
+	}
+	want := []string{"v2.0.0", "v1.10.0", "v1.10.0-rc.1", "v1.9.0", "v1.2.0", "v1.1.0", "latest", "nightly"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("tags = %s, want %s", strings.Join(names, " "), strings.Join(want, " "))
+	}
+}
+
+func TestAssignPreviousTags(t *testing.T) {
+	tags := []*tagRef{{name: "v1.9.0"}, {name: "v1.10.0"}, {name: "v1.2.0"}, {name: "v1.1.1"}, {name: "v1.1.0"}, {name: "v1.0.0"}}
+	sortTagRefs(tags)
+	releases := []*release{{tag: "v1.10.0"}, {tag: "v1.2.0"}, {tag: "v1.1.0"}, {tag: "v1.0.0"}, {tag: "unknown"}}
+	assignPreviousTags(releases, tagNames(tags))
+
+	want := map[string]string{"v1.10.0": "v1.9.0", "v1.2.0": "v1.1.1", "v1.1.0": "v1.0.0", "v1.0.0": "", "unknown": ""}
+	for _, release := range releases {
+		if release.previousTag != want[release.tag] {
+			t.Errorf("previous tag of %s = %q, want %q", release.tag, release.previousTag, want[release.tag])
+		}
+	}
+}
+
+// Serves fixed milestones, tags, releases and comparisons instead of reading them from Github
+type fakeFetcher struct {
+	milestones      []*github.Milestone
+	tags            []*tagRef
+	releases        []*github.RepositoryRelease
+	milestoneIssues []*github.Issue
+	comparison      *github.CommitsComparison
+	previousAuthors map[string]bool
+	assets          map[int64][]byte
+	tagReleases     map[string]*github.RepositoryRelease
+}
+
+func (f *fakeFetcher) readMilestones(account, repository string) ([]*github.Milestone, error) {
+	return f.milestones, nil
+}
+
+func (f *fakeFetcher) readTags(name, account, repository string) ([]*tagRef, error) {
+	return f.tags, nil
+}
+
+func (f *fakeFetcher) readReleases(name, account, repository string) ([]*github.RepositoryRelease, error) {
+	return f.releases, nil
+}
+
+func (f *fakeFetcher) readCommitDate(account, repository string, tag *tagRef) (time.Time, error) {
+	return tag.committed, nil
+}
+
+func (f *fakeFetcher) readTagDate(account, repository string, tag *tagRef) (time.Time, error) {
+	return tag.tagged, nil
+}
+
+func (f *fakeFetcher) readMilestoneIssues(account, repository string, milestone int) ([]*github.Issue, error) {
+	return f.milestoneIssues, nil
+}
+
+func (f *fakeFetcher) readComparison(account, repository, base, head string) (*github.CommitsComparison, error) {
+	if f.comparison == nil {
+		return nil, fmt.Errorf("no comparison of %s...%s", base, head)
+	}
+	return f.comparison, nil
+}
+
+func (f *fakeFetcher) hasCommitsBefore(account, repository, ref, author string) (bool, error) {
+	return f.previousAuthors[author], nil
+}
+
+func (f *fakeFetcher) readReleaseAsset(account, repository string, id int64) ([]byte, error) {
+	content, ok := f.assets[id]
+	if !ok {
+		return nil, fmt.Errorf("no asset %d", id)
+	}
+	return content, nil
+}
+
+func (f *fakeFetcher) readReleaseByTag(account, repository, tag string) (*github.RepositoryRelease, error) {
+	return f.tagReleases[tag], nil
+}
+
+// Builds a commit of the given Github login, an empty login denotes an author without Github account
+func testCommit(sha, login, name, message string) github.RepositoryCommit {
+	commit := github.RepositoryCommit{
+		SHA: github.String(sha),
+		Commit: &github.Commit{
+			Message: github.String(message),
+			Author:  &github.CommitAuthor{Name: github.String(name), Email: github.String(strings.ToLower(name) + "@example.com")},
+		},
+	}
+	if login != "" {
+		commit.Author = &github.User{Login: github.String(login)}
+	}
+	return commit
+}
+
+func TestRestFetcherPaginatesComparisons(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/api/v3/repos/noctarius/example/compare/v1.0.0...v1.1.0" {
+			http.NotFound(writer, request)
+			return
+		}
+
+		page, _ := strconv.Atoi(request.URL.Query().Get("page"))
+		if page < 3 {
+			writer.Header().Set("Link", fmt.Sprintf("<%s%s?per_page=100&page=%d>; rel=\"next\"",
+				server.URL, request.URL.Path, page+1))
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(writer, `{"total_commits":3,"commits":[{"sha":"c%d","commit":{"message":"fix: change %d"}}]}`, page, page)
+	}))
+	defer server.Close()
+	defer setupTestConfiguration(t, "[Remote \"foo\"]\n")()
+
+	client, err := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher, err := newRestFetcher("foo", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparison, err := fetcher.readComparison("noctarius", "example", "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shas := make([]string, 0)
+	for _, commit := range comparison.Commits {
+		shas = append(shas, commit.GetSHA())
+	}
+	if !reflect.DeepEqual(shas, []string{"c1", "c2", "c3"}) {
+		t.Errorf("commits = %v, want the commits of all pages", shas)
+	}
+}
+
+func TestRestFetcherEscapesComparedRefs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.EscapedPath() != "/api/v3/repos/noctarius/example/compare/release%2F1.0.0...release%2F1.1.0+build" {
+			http.NotFound(writer, request)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(writer, `{"total_commits":1,"commits":[{"sha":"c1","commit":{"message":"fix: change"}}]}`)
+	}))
+	defer server.Close()
+	defer setupTestConfiguration(t, "[Remote \"foo\"]\n")()
+
+	client, err := github.NewEnterpriseClient(server.URL+"/api/v3/", server.URL+"/api/uploads/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher, err := newRestFetcher("foo", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tags containing path separators are refs of their own, not paths of the API
+	comparison, err := fetcher.readComparison("noctarius", "example", "release/1.0.0", "release/1.1.0+build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comparison.Commits) != 1 {
+		t.Errorf("%d commits, want 1", len(comparison.Commits))
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"github.com/google/go-github/github"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"errors"
+)
+
+const (
+	graphqlBatchSize = 20
+	graphqlPageSize  = 100
+)
+
+// Retrieves tags with their commit dates and milestones of many repositories in a few paginated
+// GraphQL queries, instead of one REST call per page and tagged commit. Releases, milestone
+// issues and comparisons are still read using the REST api.
+type graphqlFetcher struct {
+	rest    *restFetcher
+	client  *github.Client
+	url     string
+	batches map[string]*graphqlBatch
+}
+
+// Repositories queried together, the batch is loaded by the first worker requesting one of them
+type graphqlBatch struct {
+	once         sync.Once
+	repositories []*github.Repository
+	results      map[string]*graphqlResult
+	err          error
+}
+
+type graphqlResult struct {
+	tags       []*tagRef
+	milestones []*github.Milestone
+	err        error
+}
+
+type graphqlPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type graphqlTagger struct {
+	Date time.Time `json:"date"`
+}
+
+type graphqlTarget struct {
+	Typename      string         `json:"__typename"`
+	Oid           string         `json:"oid"`
+	CommittedDate time.Time      `json:"committedDate"`
+	Tagger        *graphqlTagger `json:"tagger"`
+	Target        *graphqlTarget `json:"target"`
+}
+
+type graphqlRefs struct {
+	PageInfo graphqlPageInfo `json:"pageInfo"`
+	Nodes    []struct {
+		Name   string        `json:"name"`
+		Target graphqlTarget `json:"target"`
+	} `json:"nodes"`
+}
+
+type graphqlMilestones struct {
+	PageInfo graphqlPageInfo `json:"pageInfo"`
+	Nodes    []struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		State  string `json:"state"`
+		Url    string `json:"url"`
+	} `json:"nodes"`
+}
+
+type graphqlRepository struct {
+	Refs       *graphqlRefs       `json:"refs"`
+	Milestones *graphqlMilestones `json:"milestones"`
+}
+
+type graphqlError struct {
+	Type    string        `json:"type"`
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path"`
+}
+
+type graphqlResponse struct {
+	Data   map[string]*graphqlRepository `json:"data"`
+	Errors []*graphqlError               `json:"errors"`
+}
+
+func newGraphqlFetcher(name string, repositories []*github.Repository, client *github.Client) (*graphqlFetcher, error) {
+	rest, err := newRestFetcher(name, client)
+	if err != nil {
+		return nil, err
+	}
+	fetcher := &graphqlFetcher{
+		rest:    rest,
+		client:  client,
+		url:     readGraphqlUrl(name),
+		batches: make(map[string]*graphqlBatch),
+	}
+
+	for i := 0; i < len(repositories); i += graphqlBatchSize {
+		end := i + graphqlBatchSize
+		if end > len(repositories) {
+			end = len(repositories)
+		}
+
+		batch := &graphqlBatch{repositories: repositories[i:end]}
+		for _, repo := range batch.repositories {
+			fetcher.batches[strings.ToLower(repo.GetFullName())] = batch
+		}
+	}
+	return fetcher, nil
+}
+
+// Github Enterprise Server serves GraphQL at https://<host>/api/graphql, next to the REST api
+func readGraphqlUrl(name string) string {
+	apiUrl := readApiUrl(name)
+	if strings.HasSuffix(apiUrl, "/v3/") {
+		return strings.TrimSuffix(apiUrl, "v3/") + "graphql"
+	}
+	return apiUrl + "graphql"
+}
+
+func (f *graphqlFetcher) readMilestones(account, repository string) ([]*github.Milestone, error) {
+	result, err := f.result(account, repository)
+	if err != nil {
+		return nil, err
+	}
+	return result.milestones, nil
+}
+
+func (f *graphqlFetcher) readTags(name, account, repository string) ([]*tagRef, error) {
+	result, err := f.result(account, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := readReleasePattern(name, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]*tagRef, 0, len(result.tags))
+	for _, tag := range result.tags {
+		if pattern != nil && !pattern.MatchString(tag.name) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	sortTagRefs(tags)
+	return tags, nil
+}
+
+func (f *graphqlFetcher) readReleases(name, account, repository string) ([]*github.RepositoryRelease, error) {
+	return f.rest.readReleases(name, account, repository)
+}
+
+func (f *graphqlFetcher) readMilestoneIssues(account, repository string, milestone int) ([]*github.Issue, error) {
+	return f.rest.readMilestoneIssues(account, repository, milestone)
+}
+
+func (f *graphqlFetcher) readComparison(account, repository, base, head string) (*github.CommitsComparison, error) {
+	return f.rest.readComparison(account, repository, base, head)
+}
+
+func (f *graphqlFetcher) readReleaseAsset(account, repository string, id int64) ([]byte, error) {
+	return f.rest.readReleaseAsset(account, repository, id)
+}
+
+func (f *graphqlFetcher) readReleaseByTag(account, repository, tag string) (*github.RepositoryRelease, error) {
+	return f.rest.readReleaseByTag(account, repository, tag)
+}
+
+func (f *graphqlFetcher) hasCommitsBefore(account, repository, ref, author string) (bool, error) {
+	return f.rest.hasCommitsBefore(account, repository, ref, author)
+}
+
+// Commit dates are part of the queried tags, tags not pointing to a commit have no date
+func (f *graphqlFetcher) readCommitDate(account, repository string, tag *tagRef) (time.Time, error) {
+	return tag.committed, nil
+}
+
+// Tagger dates are part of the queried tags, lightweight tags have no date
+func (f *graphqlFetcher) readTagDate(account, repository string, tag *tagRef) (time.Time, error) {
+	return tag.tagged, nil
+}
+
+func (f *graphqlFetcher) result(account, repository string) (*graphqlResult, error) {
+	key := strings.ToLower(fmt.Sprintf("%s/%s", account, repository))
+	batch, ok := f.batches[key]
+	if !ok {
+		return nil, fmt.Errorf("repository %s is not part of any GraphQL query", key)
+	}
+
+	batch.once.Do(func() {
+		batch.results, batch.err = f.queryBatch(batch.repositories)
+	})
+	if batch.err != nil {
+		return nil, batch.err
+	}
+
+	result := batch.results[key]
+	if result.err != nil {
+		return nil, result.err
+	}
+	return result, nil
+}
+
+// Queries the first page of tags and milestones of all repositories of the batch, following
+// pages are queried separately for each repository requiring them
+func (f *graphqlFetcher) queryBatch(repositories []*github.Repository) (map[string]*graphqlResult, error) {
+	parameters := make([]string, 0, len(repositories)*2)
+	fields := make([]string, 0, len(repositories))
+	variables := make(map[string]interface{}, len(repositories)*2)
+
+	for i, repo := range repositories {
+		parameters = append(parameters, fmt.Sprintf("$o%d: String!, $n%d: String!", i, i))
+		fields = append(fields, fmt.Sprintf("r%d: repository(owner: $o%d, name: $n%d) { %s %s }",
+			i, i, i, graphqlRefsField(""), graphqlMilestonesField("")))
+		variables[fmt.Sprintf("o%d", i)] = repo.GetOwner().GetLogin()
+		variables[fmt.Sprintf("n%d", i)] = repo.GetName()
+	}
+
+	query := fmt.Sprintf("query(%s) { %s }", strings.Join(parameters, ", "), strings.Join(fields, " "))
+	response, err := f.query(query, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors of a single repository (e.g. NOT_FOUND) carry its alias as the first path element
+	repositoryErrors := make(map[string]error)
+	for _, e := range response.Errors {
+		if len(e.Path) == 0 {
+			return nil, fmt.Errorf("GraphQL query failed: %s", e.Message)
+		}
+		if alias, ok := e.Path[0].(string); ok {
+			repositoryErrors[alias] = fmt.Errorf("GraphQL query failed: %s", e.Message)
+		}
+	}
+
+	results := make(map[string]*graphqlResult, len(repositories))
+	for i, repo := range repositories {
+		alias := fmt.Sprintf("r%d", i)
+		result := &graphqlResult{}
+		results[strings.ToLower(repo.GetFullName())] = result
+
+		node := response.Data[alias]
+		if node == nil || node.Refs == nil || node.Milestones == nil {
+			result.err = repositoryErrors[alias]
+			if result.err == nil {
+				result.err = fmt.Errorf("GraphQL query returned no data for %s", repo.GetFullName())
+			}
+			continue
+		}
+
+		owner := repo.GetOwner().GetLogin()
+		name := repo.GetName()
+		if result.tags, result.err = f.collectTags(owner, name, node.Refs); result.err != nil {
+			continue
+		}
+		result.milestones, result.err = f.collectMilestones(owner, name, node.Milestones)
+	}
+	return results, nil
+}
+
+func (f *graphqlFetcher) collectTags(owner, name string, refs *graphqlRefs) ([]*tagRef, error) {
+	tags := make([]*tagRef, 0, len(refs.Nodes))
+	for {
+		for _, node := range refs.Nodes {
+			tag := &tagRef{name: node.Name}
+
+			// Annotated tags point to a tag object, which points to the commit
+			target := node.Target
+			if target.Typename == "Tag" && target.Target != nil {
+				tag.object = target.Oid
+				if target.Tagger != nil {
+					tag.tagged = target.Tagger.Date
+				}
+				target = *target.Target
+			}
+
+			tag.sha = target.Oid
+			tag.committed = target.CommittedDate
+			tags = append(tags, tag)
+		}
+
+		if !refs.PageInfo.HasNextPage {
+			return tags, nil
+		}
+
+		node, err := f.queryPage(owner, name, graphqlRefsField(", after: $cursor"), refs.PageInfo.EndCursor)
+		if err != nil {
+			return nil, err
+		}
+		if node.Refs == nil {
+			return nil, fmt.Errorf("GraphQL query returned no tags for %s/%s", owner, name)
+		}
+		refs = node.Refs
+	}
+}
+
+func (f *graphqlFetcher) collectMilestones(owner, name string, milestones *graphqlMilestones) ([]*github.Milestone, error) {
+	result := make([]*github.Milestone, 0, len(milestones.Nodes))
+	for {
+		for _, node := range milestones.Nodes {
+			result = append(result, &github.Milestone{
+				Number:  github.Int(node.Number),
+				Title:   github.String(node.Title),
+				State:   github.String(strings.ToLower(node.State)),
+				HTMLURL: github.String(node.Url),
+			})
+		}
+
+		if !milestones.PageInfo.HasNextPage {
+			return result, nil
+		}
+
+		node, err := f.queryPage(owner, name, graphqlMilestonesField(", after: $cursor"), milestones.PageInfo.EndCursor)
+		if err != nil {
+			return nil, err
+		}
+		if node.Milestones == nil {
+			return nil, fmt.Errorf("GraphQL query returned no milestones for %s/%s", owner, name)
+		}
+		milestones = node.Milestones
+	}
+}
+
+func (f *graphqlFetcher) queryPage(owner, name, field, cursor string) (*graphqlRepository, error) {
+	query := fmt.Sprintf("query($owner: String!, $name: String!, $cursor: String!) { "+
+		"r: repository(owner: $owner, name: $name) { %s } }", field)
+
+	response, err := f.query(query, map[string]interface{}{
+		"owner":  owner,
+		"name":   name,
+		"cursor": cursor,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Errors) > 0 {
+		return nil, fmt.Errorf("GraphQL query failed: %s", response.Errors[0].Message)
+	}
+	if response.Data["r"] == nil {
+		return nil, fmt.Errorf("GraphQL query returned no data for %s/%s", owner, name)
+	}
+	return response.Data["r"], nil
+}
+
+func (f *graphqlFetcher) query(query string, variables map[string]interface{}) (*graphqlResponse, error) {
+	ctx := context.Background()
+
+	req, err := f.client.NewRequest("POST", f.url, map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &graphqlResponse{}
+	if _, err := f.client.Do(ctx, req, response); err != nil {
+		return nil, fmt.Errorf("could not execute GraphQL query: %v", err)
+	}
+	if response.Data == nil && len(response.Errors) == 0 {
+		return nil, errors.New("GraphQL query returned no data")
+	}
+	return response, nil
+}
+
+// Tags are read completely and ordered by sortTagRefs, the api order only matters for paging
+func graphqlRefsField(arguments string) string {
+	return fmt.Sprintf("refs(refPrefix: \"refs/tags/\", first: %d%s, "+
+		"orderBy: {field: ALPHABETICAL, direction: DESC}) { "+
+		"pageInfo { hasNextPage endCursor } "+
+		"nodes { name target { __typename oid ... on Commit { committedDate } "+
+		"... on Tag { tagger { date } target { __typename oid ... on Commit { committedDate } } } } } }", graphqlPageSize, arguments)
+}
+
+func graphqlMilestonesField(arguments string) string {
+	return fmt.Sprintf("milestones(first: %d%s) { "+
+		"pageInfo { hasNextPage endCursor } "+
+		"nodes { number title state url } }", graphqlPageSize, arguments)
+}
@@ -18,7 +18,6 @@ import (
 	"io"
 	"crypto/rand"
 	"github.com/google/go-github/github"
-	"time"
 	"grm/config"
 	"github.com/denisbrodbeck/machineid"
 )
@@ -51,6 +50,9 @@ func main() {
 	app.Command("config", "Sets, gets configuration properties for remote Github users", cmdConfig)
 	app.Command("export", "Exports configuration properties for remote Github users", cmdExport)
 	app.Command("import", "Imports configuration properties for remote Github users", cmdImport)
+	app.Command("daemon", "Polls the remote Github users on a schedule and notifies about new releases", cmdDaemon)
+	app.Command("state", "Inspects, resets or rolls back the already reported releases", cmdState)
+	app.Command("cache", "Inspects or clears the cached Github API responses", cmdCache)
 	app.Command("license", "Prints all license information for vendored dependencies", cmdLicenses)
 
 	app.Run(os.Args)
@@ -156,43 +158,33 @@ func encrypt(value string, key []byte) (string, string) {
 	return base64.StdEncoding.EncodeToString(encrypted), base64.StdEncoding.EncodeToString(salt)
 }
 
-func decrypt(value, salt string, key []byte) string {
+func decrypt(value, salt string, key []byte) (string, error) {
 	data, err := base64.StdEncoding.DecodeString(value)
 	if err != nil {
-		log.Fatal("Could not decryption password: ", err)
+		return "", fmt.Errorf("Could not decode the password: %v", err)
 	}
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		log.Fatal("Could not setup password decryption: ", err)
+		return "", fmt.Errorf("Could not setup password decryption: %v", err)
 	}
 
 	aesgcm, err := cipher.NewGCM(block)
 	if err != nil {
-		log.Fatal("Could not setup password decryption: ", err)
+		return "", fmt.Errorf("Could not setup password decryption: %v", err)
 	}
 
 	iv, err := base64.StdEncoding.DecodeString(salt)
 	if err != nil {
-		log.Fatal("Could not decode the password salt: ", err)
+		return "", fmt.Errorf("Could not decode the password salt: %v", err)
 	}
 
 	decrypted, err := aesgcm.Open(nil, iv, data, nil)
 	if err != nil {
-		log.Fatal("Could not decrypt password: ", err)
+		return "", fmt.Errorf("Could not decrypt password: %v", err)
 	}
 
-	return string(decrypted)
-}
-
-func rateLimit(response *github.Response) bool {
-	if response.Remaining > 0 {
-		return false
-	}
-
-	delta := time.Now().UTC().Unix() - response.Reset.Unix()
-	time.Sleep(time.Duration(delta) * time.Nanosecond)
-	return true
+	return string(decrypted), nil
 }
 
 func hasMorePages(response *github.Response) bool {
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"net/http"
+	"grm/config"
+	"strings"
+	"fmt"
+	"encoding/json"
+	"bytes"
+	"os"
+	"os/exec"
+	"time"
+	"io"
+	"io/ioutil"
+)
+
+// A newly published release, as sent to notification sinks
+type releaseNotification struct {
+	Definition    string         `json:"definition"`
+	Repository    string         `json:"repository"`
+	RepositoryUrl string         `json:"repositoryUrl,omitempty"`
+	Release       *reportRelease `json:"release"`
+}
+
+// Delivers release notifications, e.g. to a webhook or chat
+type notificationSink interface {
+	notify(notification *releaseNotification) error
+	String() string
+}
+
+// Reads the notification sinks of a definition from the whitespace separated notify property,
+// e.g. "log slack:https://hooks.slack.com/services/... webhook:https://example.com/releases"
+func readNotificationSinks(name string) ([]notificationSink, error) {
+	value := "log"
+	if n, ok := configuration.NamedSectionGet(name, config.Remote, config.Notify, ""); ok && n != "" {
+		value = n
+	}
+
+	transport, err := newBaseTransport(name)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := &http.Client{Transport: transport, Timeout: 30 * time.Second}
+
+	sinks := make([]notificationSink, 0)
+	for _, sink := range strings.Fields(value) {
+		parts := strings.SplitN(sink, ":", 2)
+		switch {
+		case parts[0] == "log" && len(parts) == 1:
+			sinks = append(sinks, &logSink{})
+		case parts[0] == "webhook" && len(parts) == 2 && parts[1] != "":
+			sinks = append(sinks, &webhookSink{httpClient: httpClient, url: parts[1]})
+		case parts[0] == "slack" && len(parts) == 2 && parts[1] != "":
+			sinks = append(sinks, &slackSink{httpClient: httpClient, url: parts[1]})
+		case parts[0] == "exec" && len(parts) == 2 && parts[1] != "":
+			sinks = append(sinks, &execSink{command: parts[1]})
+		default:
+			return nil, fmt.Errorf("unknown notification sink: %s", sink)
+		}
+	}
+	return sinks, nil
+}
+
+// Prints notifications to stdout, like the text report
+type logSink struct{}
+
+func (s *logSink) notify(notification *releaseNotification) error {
+	release := notification.Release
+	fmt.Println(fmt.Sprintf("New %s release: %s (%s)", notification.Repository, release.Name, release.Created.Format("2006-01-02")))
+	fmt.Println("Release Notes: " + release.NotesUrl)
+	return nil
+}
+
+func (s *logSink) String() string {
+	return "log"
+}
+
+// Posts the notification as JSON document
+type webhookSink struct {
+	httpClient *http.Client
+	url        string
+}
+
+func (s *webhookSink) notify(notification *releaseNotification) error {
+	return postNotification(s.httpClient, s.url, notification)
+}
+
+func (s *webhookSink) String() string {
+	return "webhook"
+}
+
+// Posts a message to a Slack incoming webhook
+type slackSink struct {
+	httpClient *http.Client
+	url        string
+}
+
+func (s *slackSink) notify(notification *releaseNotification) error {
+	release := notification.Release
+	text := fmt.Sprintf("New %s release: <%s|%s>", notification.Repository, release.NotesUrl, release.Name)
+	if release.DownloadUrl != "" {
+		text += fmt.Sprintf(" (<%s|Download>)", release.DownloadUrl)
+	}
+	return postNotification(s.httpClient, s.url, map[string]string{"text": text})
+}
+
+func (s *slackSink) String() string {
+	return "slack"
+}
+
+// Runs a command with the notification as JSON document on stdin, the main properties are
+// also passed as environment variables. Sinks are separated by whitespace, so the command is
+// the path of an executable without arguments.
+type execSink struct {
+	command string
+}
+
+func (s *execSink) notify(notification *releaseNotification) error {
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(s.command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"GRM_DEFINITION="+notification.Definition,
+		"GRM_REPOSITORY="+notification.Repository,
+		"GRM_RELEASE_NAME="+notification.Release.Name,
+		"GRM_RELEASE_TAG="+notification.Release.Tag,
+		"GRM_RELEASE_NOTES_URL="+notification.Release.NotesUrl,
+		"GRM_RELEASE_DOWNLOAD_URL="+notification.Release.DownloadUrl,
+	)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("command %s failed: %v", s.command, err)
+	}
+	return nil
+}
+
+func (s *execSink) String() string {
+	return "exec"
+}
+
+func postNotification(httpClient *http.Client, url string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	response, err := httpClient.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("could not post notification: %v", err)
+	}
+	io.Copy(ioutil.Discard, response.Body)
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("could not post notification: %s", response.Status)
+	}
+	return nil
+}
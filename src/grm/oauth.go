@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"encoding/json"
+	"fmt"
+	"time"
+	"errors"
+)
+
+const (
+	defaultOAuthUrl   = "https://github.com/"
+	defaultOAuthScope = "repo read:org"
+	deviceGrantType   = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+type deviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationUri string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+type deviceToken struct {
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	Interval         int    `json:"interval"`
+}
+
+// Runs the OAuth device authorization flow against the given base url and returns the access token.
+// The prompt callback is used to present the user code and verification url to the user.
+func runDeviceFlow(client *http.Client, oauthUrl, clientId, scope string, prompt func(code *deviceCode)) (string, error) {
+	if !strings.HasSuffix(oauthUrl, "/") {
+		oauthUrl = oauthUrl + "/"
+	}
+
+	code := &deviceCode{}
+	err := postOAuthForm(client, oauthUrl+"login/device/code", url.Values{
+		"client_id": {clientId},
+		"scope":     {scope},
+	}, code)
+	if err != nil {
+		return "", err
+	}
+	if code.DeviceCode == "" {
+		return "", errors.New("no device code received")
+	}
+
+	prompt(code)
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+
+	for {
+		time.Sleep(interval)
+
+		if code.ExpiresIn > 0 && time.Now().After(deadline) {
+			return "", errors.New("the device code expired, please restart the authorization")
+		}
+
+		token := &deviceToken{}
+		err := postOAuthForm(client, oauthUrl+"login/oauth/access_token", url.Values{
+			"client_id":   {clientId},
+			"device_code": {code.DeviceCode},
+			"grant_type":  {deviceGrantType},
+		}, token)
+		if err != nil {
+			return "", err
+		}
+
+		switch token.Error {
+		case "":
+			if token.AccessToken == "" {
+				return "", errors.New("no access token received")
+			}
+			return token.AccessToken, nil
+
+		case "authorization_pending":
+			continue
+
+		case "slow_down":
+			// The server asks for an increased polling interval, by default 5 seconds
+			if token.Interval > 0 {
+				interval = time.Duration(token.Interval) * time.Second
+			} else {
+				interval += 5 * time.Second
+			}
+			continue
+
+		case "expired_token":
+			return "", errors.New("the device code expired, please restart the authorization")
+
+		case "access_denied":
+			return "", errors.New("the authorization request was denied")
+		}
+
+		return "", fmt.Errorf("authorization failed: %s %s", token.Error, token.ErrorDescription)
+	}
+}
+
+func postOAuthForm(client *http.Client, endpoint string, values url.Values, result interface{}) error {
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	response, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from %s: %s", endpoint, response.Status)
+	}
+
+	return json.NewDecoder(response.Body).Decode(result)
+}
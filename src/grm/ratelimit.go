@@ -0,0 +1,249 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+	"strconv"
+	"fmt"
+	"math/rand"
+	"io/ioutil"
+	"bytes"
+	"strings"
+	"io"
+	"github.com/vbauerster/mpb/decor"
+)
+
+const (
+	maxRateLimitRetries   = 10
+	maxServerErrorRetries = 5
+	backoffBase           = time.Second
+	backoffMax            = 2 * time.Minute
+	secondaryLimitWait    = time.Minute
+	secondaryLimitMax     = 15 * time.Minute
+)
+
+// Shared by all Github clients, so that concurrent workers stop together when a limit is hit
+var githubRateLimiter = newRateLimiter()
+
+// Coordinates waits for primary and secondary rate limits across all requests
+type rateLimiter struct {
+	mutex        sync.Mutex
+	blockedUntil time.Time
+	reason       string
+	now          func() time.Time
+	sleep        func(d time.Duration)
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		now:   time.Now,
+		sleep: time.Sleep,
+	}
+}
+
+// Blocks all requests for at least the given duration
+func (l *rateLimiter) block(wait time.Duration, reason string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	until := l.now().Add(wait)
+	if until.After(l.blockedUntil) {
+		l.blockedUntil = until
+		l.reason = reason
+	}
+}
+
+// Waits until the limiter is no longer blocked or the request is cancelled
+func (l *rateLimiter) wait(req *http.Request) error {
+	for {
+		l.mutex.Lock()
+		remaining := l.blockedUntil.Sub(l.now())
+		l.mutex.Unlock()
+
+		if remaining <= 0 {
+			return nil
+		}
+
+		// Sleep in small steps to notice cancellation and extended blocks
+		if remaining > time.Second {
+			remaining = time.Second
+		}
+
+		select {
+		case <-req.Context().Done():
+			return req.Context().Err()
+		default:
+			l.sleep(remaining)
+		}
+	}
+}
+
+// Describes the currently active wait, an empty string if requests aren't blocked
+func (l *rateLimiter) status() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	remaining := l.blockedUntil.Sub(l.now())
+	if remaining <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("waiting %s for %s", remaining/time.Second*time.Second, l.reason)
+}
+
+// Retries requests which failed due to rate limits or server errors
+type rateLimitTransport struct {
+	limiter   *rateLimiter
+	transport http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	serverErrors := 0
+	secondaryLimits := 0
+
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.wait(req); err != nil {
+			return nil, err
+		}
+
+		if attempt > 0 && req.Body != nil {
+			// Requests with a body can only be retried if the body can be recreated
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("cannot retry request to %s", req.URL)
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = cloneRequest(req)
+			req.Body = body
+		}
+
+		response, err := t.transport.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			wait   time.Duration
+			reason string
+			retry  bool
+		)
+
+		switch {
+		case response.StatusCode == http.StatusForbidden || response.StatusCode == http.StatusTooManyRequests:
+			wait, reason, retry = t.evaluateRateLimit(response, secondaryLimits)
+			if retry && reason == "secondary rate limit" {
+				secondaryLimits++
+			}
+			retry = retry && attempt < maxRateLimitRetries
+
+		case response.StatusCode >= 500 && response.StatusCode != http.StatusNotImplemented:
+			wait, reason = backoff(serverErrors), "server error"
+			retry = serverErrors < maxServerErrorRetries
+			serverErrors++
+
+		default:
+			// The last request used up the rate limit, wait for the reset before continuing
+			if remaining, reset, ok := parseRateLimit(response); ok && remaining == 0 {
+				t.limiter.block(reset.Sub(t.limiter.now())+time.Second, "rate limit reset")
+				if err := t.limiter.wait(req); err != nil {
+					response.Body.Close()
+					return nil, err
+				}
+			}
+			return response, nil
+		}
+
+		if !retry {
+			return response, nil
+		}
+
+		io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+
+		t.limiter.block(wait, reason)
+	}
+}
+
+// Evaluates 403 and 429 responses, returns the time to wait and whether the request should be retried
+func (t *rateLimitTransport) evaluateRateLimit(response *http.Response, secondaryLimits int) (time.Duration, string, bool) {
+	if retryAfter := response.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.ParseInt(retryAfter, 10, 64); err == nil {
+			return time.Duration(seconds) * time.Second, "secondary rate limit", true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return date.Sub(t.limiter.now()), "secondary rate limit", true
+		}
+	}
+
+	if remaining, reset, ok := parseRateLimit(response); ok && remaining == 0 {
+		return reset.Sub(t.limiter.now()) + time.Second, "rate limit reset", true
+	}
+
+	// Secondary (abuse) rate limits without Retry-After header can only be recognized by the message
+	data, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return 0, "", false
+	}
+
+	message := strings.ToLower(string(data))
+	if strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse") {
+		return secondaryBackoff(secondaryLimits), "secondary rate limit", true
+	}
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		return backoff(secondaryLimits), "too many requests", true
+	}
+	return 0, "", false
+}
+
+func parseRateLimit(response *http.Response) (int, time.Time, bool) {
+	remaining, err := strconv.Atoi(response.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	reset, err := strconv.ParseInt(response.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	return remaining, time.Unix(reset, 0), true
+}
+
+// Calculates an exponential backoff with jitter, the result is between half and the full backoff
+func backoff(retry int) time.Duration {
+	wait := backoffBase << uint(retry)
+	if wait > backoffMax || wait <= 0 {
+		wait = backoffMax
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// Calculates the wait after repeated secondary rate limits, doubling from a minute up to 15 minutes.
+// The jitter shortens the wait by up to a quarter, but never below a minute as recommended by Github.
+func secondaryBackoff(retry int) time.Duration {
+	wait := secondaryLimitWait << uint(retry)
+	if wait > secondaryLimitMax || wait <= 0 {
+		wait = secondaryLimitMax
+	}
+	wait -= time.Duration(rand.Int63n(int64(wait/4) + 1))
+	if wait < secondaryLimitWait {
+		wait = secondaryLimitWait
+	}
+	return wait
+}
+
+// Progress bar decorator showing the currently active rate limit wait
+func rateLimitDecorator(limiter *rateLimiter) decor.Decorator {
+	wc := decor.WC{}
+	wc.BuildFormat()
+	return decor.DecoratorFunc(func(s *decor.Statistics, widthAccumulator chan<- int, widthDistributor <-chan int) string {
+		status := limiter.status()
+		if status != "" {
+			status = " " + status
+		}
+		return wc.FormatMsg(status, widthAccumulator, widthDistributor)
+	})
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"time"
+	"strconv"
+	"io/ioutil"
+	"strings"
+)
+
+// A fake clock, sleeping advances the time instantly
+type fakeClock struct {
+	current time.Time
+	slept   time.Duration
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.current
+}
+
+func (c *fakeClock) sleep(d time.Duration) {
+	c.current = c.current.Add(d)
+	c.slept += d
+}
+
+// A fake response of the test server, the headers may refer to the current fake time
+type fakeResponse struct {
+	status  int
+	headers func(now time.Time) map[string]string
+	body    string
+}
+
+func newRateLimitTestTransport(t *testing.T, responses []fakeResponse) (*rateLimitTransport, *fakeClock, *int, *httptest.Server) {
+	clock := &fakeClock{current: time.Unix(1500000000, 0)}
+	limiter := newRateLimiter()
+	limiter.now = clock.now
+	limiter.sleep = clock.sleep
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if requests >= len(responses) {
+			t.Errorf("unexpected request %d", requests+1)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		response := responses[requests]
+		requests++
+
+		if response.headers != nil {
+			for k, v := range response.headers(clock.now()) {
+				writer.Header().Set(k, v)
+			}
+		}
+		writer.WriteHeader(response.status)
+		writer.Write([]byte(response.body))
+	}))
+
+	transport := &rateLimitTransport{limiter: limiter, transport: http.DefaultTransport}
+	return transport, clock, &requests, server
+}
+
+func rateLimitHeaders(remaining int, resetIn time.Duration) func(now time.Time) map[string]string {
+	return func(now time.Time) map[string]string {
+		return map[string]string{
+			"X-RateLimit-Remaining": strconv.Itoa(remaining),
+			"X-RateLimit-Reset":     strconv.FormatInt(now.Add(resetIn).Unix(), 10),
+		}
+	}
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	ok := fakeResponse{status: http.StatusOK, body: "{}"}
+
+	tests := []struct {
+		name       string
+		responses  []fakeResponse
+		status     int
+		requests   int
+		minSlept   time.Duration
+		maxSlept   time.Duration
+		bodySuffix string
+	}{
+		{
+			name: "primary rate limit waits for reset",
+			responses: []fakeResponse{
+				{status: http.StatusForbidden, headers: rateLimitHeaders(0, time.Minute), body: `{"message":"API rate limit exceeded"}`},
+				ok,
+			},
+			status: http.StatusOK, requests: 2, minSlept: time.Minute, maxSlept: time.Minute + time.Second,
+		},
+		{
+			name: "retry-after seconds",
+			responses: []fakeResponse{
+				{status: http.StatusTooManyRequests, headers: func(time.Time) map[string]string {
+					return map[string]string{"Retry-After": "30"}
+				}},
+				ok,
+			},
+			status: http.StatusOK, requests: 2, minSlept: 30 * time.Second, maxSlept: 30 * time.Second,
+		},
+		{
+			name: "retry-after date",
+			responses: []fakeResponse{
+				{status: http.StatusForbidden, headers: func(now time.Time) map[string]string {
+					return map[string]string{"Retry-After": now.Add(2 * time.Minute).UTC().Format(http.TimeFormat)}
+				}},
+				ok,
+			},
+			status: http.StatusOK, requests: 2, minSlept: 2 * time.Minute, maxSlept: 2 * time.Minute,
+		},
+		{
+			name: "secondary rate limit from body",
+			responses: []fakeResponse{
+				{status: http.StatusForbidden, headers: rateLimitHeaders(4000, time.Hour),
+					body: `{"message":"You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`},
+				{status: http.StatusForbidden, body: `{"message":"You have triggered an abuse detection mechanism."}`},
+				ok,
+			},
+			status: http.StatusOK, requests: 3, minSlept: secondaryLimitWait + 3*secondaryLimitWait/2, maxSlept: 3 * secondaryLimitWait,
+		},
+		{
+			name: "server errors back off and retry",
+			responses: []fakeResponse{
+				{status: http.StatusBadGateway},
+				{status: http.StatusServiceUnavailable},
+				ok,
+			},
+			status: http.StatusOK, requests: 3, minSlept: backoffBase/2 + backoffBase, maxSlept: backoffBase + 2*backoffBase,
+		},
+		{
+			name: "server errors give up after retries",
+			responses: []fakeResponse{
+				{status: http.StatusInternalServerError}, {status: http.StatusInternalServerError},
+				{status: http.StatusInternalServerError}, {status: http.StatusInternalServerError},
+				{status: http.StatusInternalServerError}, {status: http.StatusInternalServerError, body: "final"},
+			},
+			status: http.StatusInternalServerError, requests: maxServerErrorRetries + 1,
+			minSlept: 31 * backoffBase / 2, maxSlept: 31 * backoffBase, bodySuffix: "final",
+		},
+		{
+			name: "not implemented is not retried",
+			responses: []fakeResponse{
+				{status: http.StatusNotImplemented},
+			},
+			status: http.StatusNotImplemented, requests: 1,
+		},
+		{
+			name: "forbidden without rate limit passes through",
+			responses: []fakeResponse{
+				{status: http.StatusForbidden, headers: rateLimitHeaders(4000, time.Hour),
+					body: `{"message":"Resource not accessible by integration"}`},
+			},
+			status: http.StatusForbidden, requests: 1, bodySuffix: `"Resource not accessible by integration"}`,
+		},
+		{
+			name: "exhausted rate limit blocks following requests",
+			responses: []fakeResponse{
+				{status: http.StatusOK, headers: rateLimitHeaders(0, 10*time.Second), body: "last"},
+			},
+			status: http.StatusOK, requests: 1, minSlept: 10 * time.Second, maxSlept: 11 * time.Second, bodySuffix: "last",
+		},
+	}
+
+	for _, test := range tests {
+		transport, clock, requests, server := newRateLimitTestTransport(t, test.responses)
+
+		request, _ := http.NewRequest("GET", server.URL, nil)
+		response, err := transport.RoundTrip(request)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			server.Close()
+			continue
+		}
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		server.Close()
+
+		if response.StatusCode != test.status {
+			t.Errorf("%s: status = %d, want %d", test.name, response.StatusCode, test.status)
+		}
+		if *requests != test.requests {
+			t.Errorf("%s: requests = %d, want %d", test.name, *requests, test.requests)
+		}
+		if clock.slept < test.minSlept || clock.slept > test.maxSlept {
+			t.Errorf("%s: slept %s, want between %s and %s", test.name, clock.slept, test.minSlept, test.maxSlept)
+		}
+		if test.bodySuffix != "" && !strings.HasSuffix(string(body), test.bodySuffix) {
+			t.Errorf("%s: body = %q, want suffix %q", test.name, body, test.bodySuffix)
+		}
+	}
+}
+
+func TestSecondaryBackoff(t *testing.T) {
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{0, time.Minute, time.Minute},
+		{1, 90 * time.Second, 2 * time.Minute},
+		{3, 6 * time.Minute, 8 * time.Minute},
+		{4, 45 * time.Minute / 4, secondaryLimitMax},
+		{9, 45 * time.Minute / 4, secondaryLimitMax},
+		{70, 45 * time.Minute / 4, secondaryLimitMax},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 20; i++ {
+			if wait := secondaryBackoff(test.retry); wait < test.min || wait > test.max {
+				t.Errorf("retry %d: wait = %s, want between %s and %s", test.retry, wait, test.min, test.max)
+				break
+			}
+		}
+	}
+}
+
+func TestRateLimiterStatus(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1500000000, 0)}
+	limiter := newRateLimiter()
+	limiter.now = clock.now
+	limiter.sleep = clock.sleep
+
+	if status := limiter.status(); status != "" {
+		t.Errorf("status of unblocked limiter = %q", status)
+	}
+
+	limiter.block(90*time.Second, "rate limit reset")
+	limiter.block(30*time.Second, "server error")
+	if status := limiter.status(); status != "waiting 1m30s for rate limit reset" {
+		t.Errorf("status = %q", status)
+	}
+
+	clock.sleep(90 * time.Second)
+	if status := limiter.status(); status != "" {
+		t.Errorf("status after the block expired = %q", status)
+	}
+}
@@ -0,0 +1,516 @@
+package main
+
+import (
+	"io"
+	"fmt"
+	"time"
+	"encoding/json"
+	"log"
+	"strings"
+	"sort"
+	"grm/version"
+)
+
+// Version of the JSON report document, must be increased on any
+// incompatible change to the document structure
+const reportDocumentVersion = 1
+
+var reportFormats = []string{"text", "json", "template"}
+
+var reportOrders = []string{"definition", "chronological"}
+
+type reportDocument struct {
+	Version      int                 `json:"version"`
+	Definition   string              `json:"definition"`
+	Definitions  []string            `json:"definitions,omitempty"`
+	Since        time.Time           `json:"since"`
+	Until        *time.Time          `json:"until,omitempty"`
+	Repositories []*reportRepository `json:"repositories"`
+	Skipped      []*reportSkipped    `json:"skipped,omitempty"`
+}
+
+type reportSkipped struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+}
+
+type reportRepository struct {
+	Name       string           `json:"name"`
+	Owner      string           `json:"owner,omitempty"`
+	Url        string           `json:"url,omitempty"`
+	Definition string           `json:"definition,omitempty"`
+	Releases   []*reportRelease `json:"releases"`
+}
+
+type reportRelease struct {
+	Repository      string                `json:"-"`
+	Definition      string                `json:"-"`
+	Name            string                `json:"name"`
+	Tag             string                `json:"tag"`
+	Version         string                `json:"version,omitempty"`
+	Bump            string                `json:"bump,omitempty"`
+	Created         time.Time             `json:"created"`
+	NotesUrl        string                `json:"notesUrl"`
+	Milestone       *reportMilestone      `json:"milestone,omitempty"`
+	DownloadUrl     string                `json:"downloadUrl,omitempty"`
+	DownloadPageUrl string                `json:"downloadPageUrl,omitempty"`
+	DownloadStatus  string                `json:"downloadStatus,omitempty"`
+	DownloadReason  string                `json:"downloadReason,omitempty"`
+	DownloadLinks   []*reportDownloadLink `json:"downloadLinks,omitempty"`
+	ReleaseUrl      string                `json:"releaseUrl,omitempty"`
+	Body            string                `json:"body,omitempty"`
+	Published       *time.Time            `json:"published,omitempty"`
+	Draft           bool                  `json:"draft,omitempty"`
+	Prerelease      bool                  `json:"prerelease,omitempty"`
+	Author          string                `json:"author,omitempty"`
+	Assets          []*reportAsset        `json:"assets,omitempty"`
+	Changelog       *reportChangelog      `json:"changelog,omitempty"`
+	Contributors    []*reportContributor  `json:"contributors,omitempty"`
+
+	version *version.Version
+}
+
+type reportDownloadLink struct {
+	Name   string `json:"name"`
+	Url    string `json:"url"`
+	Status string `json:"status,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type reportAsset struct {
+	Name          string `json:"name"`
+	Url           string `json:"url"`
+	Size          int    `json:"size"`
+	ContentType   string `json:"contentType,omitempty"`
+	DownloadCount int    `json:"downloadCount"`
+	Sha256        string `json:"sha256,omitempty"`
+}
+
+type reportContributor struct {
+	Login     string `json:"login,omitempty"`
+	Name      string `json:"name,omitempty"`
+	FirstTime bool   `json:"firstTime,omitempty"`
+}
+
+type reportChangelog struct {
+	Sections  []*reportChangelogSection `json:"sections"`
+	More      int                       `json:"more,omitempty"`
+	MoreUrl   string                    `json:"moreUrl,omitempty"`
+	Truncated bool                      `json:"truncated,omitempty"`
+}
+
+type reportChangelogSection struct {
+	Heading string                  `json:"heading"`
+	Entries []*reportChangelogEntry `json:"entries"`
+}
+
+type reportChangelogEntry struct {
+	Title       string `json:"title"`
+	Number      int    `json:"number,omitempty"`
+	Sha         string `json:"sha,omitempty"`
+	Url         string `json:"url"`
+	PullRequest bool   `json:"pullRequest,omitempty"`
+	Author      string `json:"author,omitempty"`
+}
+
+type reportMilestone struct {
+	Title string `json:"title"`
+	Url   string `json:"url"`
+	State string `json:"state"`
+}
+
+func isValidReportFormat(format string) bool {
+	for _, f := range reportFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidReportOrder(order string) bool {
+	for _, o := range reportOrders {
+		if o == order {
+			return true
+		}
+	}
+	return false
+}
+
+func printReport(writer io.Writer, format string, tmpl reportTemplateRenderer,
+	definitions []string, order string, dates dateRange, repositories []*repository) {
+
+	switch format {
+	case "json":
+		printJsonReport(writer, definitions, dates, repositories)
+	case "template":
+		printTemplateReport(writer, tmpl, definitions, dates, repositories)
+	default:
+		printTextReport(writer, definitions, order, repositories)
+	}
+}
+
+func printTextReport(writer io.Writer, definitions []string, order string, repositories []*repository) {
+	switch {
+	case order == "chronological":
+		printChronologicalTextReport(writer, repositories)
+
+	case len(definitions) > 1:
+		for _, definition := range definitions {
+			reps := make([]*repository, 0)
+			for _, rep := range repositories {
+				if rep.definition == definition {
+					reps = append(reps, rep)
+				}
+			}
+
+			fmt.Fprintln(writer, fmt.Sprintf("Definition %s", definition))
+			fmt.Fprintln(writer, fmt.Sprintf("Found %d repositories", len(reps)))
+			for _, rep := range reps {
+				for _, rel := range reportedReleases(rep) {
+					printTextRelease(writer, rep, rel)
+				}
+			}
+		}
+
+	default:
+		fmt.Fprintln(writer, fmt.Sprintf("Found %d repositories", len(repositories)))
+		for _, rep := range repositories {
+			for _, rel := range reportedReleases(rep) {
+				printTextRelease(writer, rep, rel)
+			}
+		}
+	}
+
+	printTextSkipped(writer, repositories)
+}
+
+// Prints the releases of all repositories (and definitions) as a single list, oldest first
+func printChronologicalTextReport(writer io.Writer, repositories []*repository) {
+	type entry struct {
+		rep *repository
+		rel *release
+	}
+
+	entries := make([]*entry, 0)
+	for _, rep := range repositories {
+		for _, rel := range reportedReleases(rep) {
+			entries = append(entries, &entry{rep: rep, rel: rel})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].rel.created.Before(entries[j].rel.created)
+	})
+
+	fmt.Fprintln(writer, fmt.Sprintf("Found %d repositories", len(repositories)))
+	for _, e := range entries {
+		printTextRelease(writer, e.rep, e.rel)
+	}
+}
+
+func printTextRelease(writer io.Writer, rep *repository, rel *release) {
+	marker := ""
+	if rel.draft {
+		marker += " [draft]"
+	}
+	if rel.prerelease {
+		marker += " [pre-release]"
+	}
+	fmt.Fprintln(writer, fmt.Sprintf("New %s release: %s (%s)%s", rep.name, rel.name, rel.created.Format("2006-01-02"), marker))
+	fmt.Fprintln(writer, "Release Notes: "+rel.notesUrl)
+	if rel.releaseUrl != "" {
+		fmt.Fprintln(writer, "Release: "+rel.releaseUrl)
+	}
+	if rel.downloadUrl != "" {
+		if rel.downloadVerification != nil && !rel.downloadVerification.Verified {
+			fmt.Fprintln(writer, fmt.Sprintf("Download: %s (unverified: %s)", rel.downloadUrl, rel.downloadVerification.Reason))
+		} else {
+			fmt.Fprintln(writer, "Download: "+rel.downloadUrl)
+		}
+	}
+	for _, link := range rel.downloadLinks {
+		if link.verification != nil && !link.verification.Verified {
+			fmt.Fprintln(writer, fmt.Sprintf("Download (%s): %s (unverified: %s)", link.name, link.url, link.verification.Reason))
+		} else {
+			fmt.Fprintln(writer, fmt.Sprintf("Download (%s): %s", link.name, link.url))
+		}
+	}
+	if rel.downloadPageUrl != "" {
+		fmt.Fprintln(writer, "Package: "+rel.downloadPageUrl)
+	}
+	if len(rel.assets) > 0 {
+		fmt.Fprintln(writer, "Assets:")
+		for _, asset := range rel.assets {
+			fmt.Fprintln(writer, fmt.Sprintf("  - %s (%s, %d downloads): %s",
+				asset.name, formatSize(asset.size), asset.downloadCount, asset.url))
+			if asset.sha256 != "" {
+				fmt.Fprintln(writer, fmt.Sprintf("    SHA-256: %s", asset.sha256))
+			}
+		}
+	}
+	if rel.changelog != nil && (len(rel.changelog.sections) > 0 || rel.changelog.more > 0) {
+		fmt.Fprintln(writer, "Changes:")
+		for _, section := range rel.changelog.sections {
+			fmt.Fprintln(writer, fmt.Sprintf("  %s:", section.heading))
+			for _, entry := range section.entries {
+				fmt.Fprintln(writer, fmt.Sprintf("    - %s (%s)", entry.title, entryReference(entry)))
+			}
+		}
+		if rel.changelog.more > 0 {
+			fmt.Fprintln(writer, fmt.Sprintf("  and %d more: %s", rel.changelog.more, rel.changelog.moreUrl))
+		} else if rel.changelog.truncated {
+			fmt.Fprintln(writer, fmt.Sprintf("  and possibly more: %s", rel.changelog.moreUrl))
+		}
+	}
+	if len(rel.contributors) > 0 {
+		credits := make([]string, 0, len(rel.contributors))
+		for _, c := range rel.contributors {
+			credits = append(credits, contributorCredit(c))
+		}
+		fmt.Fprintln(writer, "Contributors: "+strings.Join(credits, ", "))
+	}
+	fmt.Fprintln(writer, "")
+}
+
+func printTextSkipped(writer io.Writer, repositories []*repository) {
+	skipped := skippedReleases(repositories)
+	if len(skipped) > 0 {
+		names := make([]string, 0, len(skipped))
+		for _, entry := range skipped {
+			names = append(names, fmt.Sprintf("%s %s", entry.Repository, entry.Tag))
+		}
+		fmt.Fprintln(writer, fmt.Sprintf("Skipped %d releases without matching milestone: %s", len(skipped), strings.Join(names, ", ")))
+	}
+}
+
+func printJsonReport(writer io.Writer, definitions []string, dates dateRange, repositories []*repository) {
+	document := buildReportDocument(definitions, dates, repositories)
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		log.Fatal("Could not write json report: ", err)
+	}
+}
+
+// Reports of multiple definitions list all definitions and tag every repository with its definition
+func buildReportDocument(definitions []string, dates dateRange, repositories []*repository) *reportDocument {
+	multiple := len(definitions) > 1
+
+	document := &reportDocument{
+		Version:      reportDocumentVersion,
+		Definition:   strings.Join(definitions, ","),
+		Since:        dates.since,
+		Repositories: make([]*reportRepository, 0),
+	}
+
+	if !dates.until.IsZero() {
+		until := dates.until
+		document.Until = &until
+	}
+
+	if multiple {
+		document.Definitions = definitions
+	}
+
+	for _, rep := range repositories {
+		releases := reportedReleases(rep)
+		if len(releases) == 0 {
+			continue
+		}
+
+		reportRep := &reportRepository{
+			Name:     rep.name,
+			Owner:    rep.owner,
+			Url:      rep.url,
+			Releases: make([]*reportRelease, 0, len(releases)),
+		}
+
+		if multiple {
+			reportRep.Definition = rep.definition
+		}
+
+		for _, rel := range releases {
+			reportRel := &reportRelease{
+				Repository:      rep.name,
+				Definition:      rep.definition,
+				Name:            rel.name,
+				Tag:             rel.tag,
+				Bump:            releaseClassification(rel),
+				Created:         rel.created,
+				NotesUrl:        rel.notesUrl,
+				DownloadUrl:     rel.downloadUrl,
+				DownloadPageUrl: rel.downloadPageUrl,
+				ReleaseUrl:      rel.releaseUrl,
+				Body:            rel.body,
+				Draft:           rel.draft,
+				Prerelease:      rel.prerelease,
+				Author:          rel.author,
+				version:         rel.version,
+			}
+
+			if rel.downloadVerification != nil {
+				reportRel.DownloadStatus = "verified"
+				if !rel.downloadVerification.Verified {
+					reportRel.DownloadStatus = "unverified"
+					reportRel.DownloadReason = rel.downloadVerification.Reason
+				}
+			}
+
+			for _, link := range rel.downloadLinks {
+				reportLink := &reportDownloadLink{Name: link.name, Url: link.url}
+				if link.verification != nil {
+					reportLink.Status = "verified"
+					if !link.verification.Verified {
+						reportLink.Status = "unverified"
+						reportLink.Reason = link.verification.Reason
+					}
+				}
+				reportRel.DownloadLinks = append(reportRel.DownloadLinks, reportLink)
+			}
+
+			if rel.version != nil {
+				reportRel.Version = rel.version.String()
+			}
+
+			if !rel.published.IsZero() {
+				published := rel.published
+				reportRel.Published = &published
+			}
+
+			for _, asset := range rel.assets {
+				reportRel.Assets = append(reportRel.Assets, &reportAsset{
+					Name:          asset.name,
+					Url:           asset.url,
+					Size:          asset.size,
+					ContentType:   asset.contentType,
+					DownloadCount: asset.downloadCount,
+					Sha256:        asset.sha256,
+				})
+			}
+
+			if rel.changelog != nil {
+				reportRel.Changelog = buildReportChangelog(rel.changelog)
+			}
+
+			for _, c := range rel.contributors {
+				reportRel.Contributors = append(reportRel.Contributors, &reportContributor{
+					Login:     c.login,
+					Name:      c.name,
+					FirstTime: c.firstTime,
+				})
+			}
+
+			if rel.milestone != nil {
+				reportRel.Milestone = &reportMilestone{
+					Title: rel.milestone.GetTitle(),
+					Url:   rel.milestoneUrl,
+					State: rel.milestoneState,
+				}
+			}
+
+			reportRep.Releases = append(reportRep.Releases, reportRel)
+		}
+
+		document.Repositories = append(document.Repositories, reportRep)
+	}
+
+	document.Skipped = skippedReleases(repositories)
+	return document
+}
+
+func buildReportChangelog(c *changelog) *reportChangelog {
+	result := &reportChangelog{
+		Sections:  make([]*reportChangelogSection, 0, len(c.sections)),
+		More:      c.more,
+		Truncated: c.truncated,
+	}
+	if c.more > 0 || c.truncated {
+		result.MoreUrl = c.moreUrl
+	}
+
+	for _, section := range c.sections {
+		reportSection := &reportChangelogSection{
+			Heading: section.heading,
+			Entries: make([]*reportChangelogEntry, 0, len(section.entries)),
+		}
+		for _, entry := range section.entries {
+			reportSection.Entries = append(reportSection.Entries, &reportChangelogEntry{
+				Title:       entry.title,
+				Number:      entry.number,
+				Sha:         entry.sha,
+				Url:         entry.url,
+				PullRequest: entry.pullRequest,
+				Author:      entry.author,
+			})
+		}
+		result.Sections = append(result.Sections, reportSection)
+	}
+	return result
+}
+
+// Selects the releases of a repository which are part of the report
+func reportedReleases(rep *repository) []*release {
+	releases := make([]*release, 0)
+	for _, rel := range rep.releases {
+		if rel.notesUrl != "" {
+			releases = append(releases, rel)
+		}
+	}
+	return releases
+}
+
+// Lists the releases dropped from the report due to a missing milestone
+func skippedReleases(repositories []*repository) []*reportSkipped {
+	skipped := make([]*reportSkipped, 0)
+	for _, rep := range repositories {
+		for _, rel := range rep.releases {
+			if rel.notesUrl == "" {
+				skipped = append(skipped, &reportSkipped{
+					Repository: rep.name,
+					Tag:        rel.tag,
+				})
+			}
+		}
+	}
+	return skipped
+}
+
+func printErrorSummary(writer io.Writer, repositoryErrors []*repositoryError) {
+	failed := make(map[string]bool)
+	for _, e := range repositoryErrors {
+		failed[e.repository] = true
+	}
+
+	fmt.Fprintln(writer, fmt.Sprintf("%d errors in %d repositories:", len(repositoryErrors), len(failed)))
+	for _, e := range repositoryErrors {
+		fmt.Fprintln(writer, fmt.Sprintf("\t%s: %v", e.repository, e.err))
+	}
+}
+
+// Entries are referenced by issue or pull request number, plain commits by their short sha
+func entryReference(entry *changelogEntry) string {
+	if entry.number == 0 && len(entry.sha) >= 7 {
+		return entry.sha[:7]
+	}
+	return fmt.Sprintf("#%d", entry.number)
+}
+
+// Credits a contributor by login and display name, e.g. "@jdoe (John Doe, first-time)"
+func contributorCredit(c *contributor) string {
+	details := make([]string, 0, 2)
+	credit := c.name
+	if c.login != "" {
+		credit = "@" + c.login
+		if c.name != "" && c.name != c.login {
+			details = append(details, c.name)
+		}
+	}
+	if c.firstTime {
+		details = append(details, "first-time")
+	}
+	if len(details) > 0 {
+		credit += fmt.Sprintf(" (%s)", strings.Join(details, ", "))
+	}
+	return credit
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"bytes"
+	"strings"
+	"time"
+)
+
+func TestPrintTextReportOrder(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2018, 5, d, 12, 0, 0, 0, time.UTC)
+	}
+	testRelease := func(tag string, created time.Time) *release {
+		return &release{name: tag, tag: tag, created: created, notesUrl: "https://github.com/example/releases/tag/" + tag}
+	}
+	repositories := []*repository{
+		{name: "alpha", owner: "a", definition: "a", releases: []*release{testRelease("v1.1.0", day(20)), testRelease("v1.0.0", day(3))}},
+		{name: "beta", owner: "b", definition: "b", releases: []*release{testRelease("v2.0.0", day(10))}},
+		// Releases without notes url aren't reported
+		{name: "gamma", owner: "b", definition: "b", releases: []*release{{name: "v0.1.0", tag: "v0.1.0", created: day(1)}}},
+	}
+
+	tests := []struct {
+		name        string
+		definitions []string
+		order       string
+		want        []string
+	}{
+		{"grouped by definition", []string{"a", "b"}, "", []string{"alpha v1.1.0", "alpha v1.0.0", "beta v2.0.0"}},
+		{"chronological across definitions", []string{"a", "b"}, "chronological", []string{"alpha v1.0.0", "beta v2.0.0", "alpha v1.1.0"}},
+		{"chronological single definition", []string{"a"}, "chronological", []string{"alpha v1.0.0", "beta v2.0.0", "alpha v1.1.0"}},
+	}
+
+	for _, test := range tests {
+		buffer := new(bytes.Buffer)
+		printReport(buffer, "text", nil, test.definitions, test.order, dateRange{}, repositories)
+
+		got := make([]string, 0)
+		for _, line := range strings.Split(buffer.String(), "\n") {
+			if strings.HasPrefix(line, "New ") {
+				fields := strings.Fields(line)
+				got = append(got, fields[1]+" "+fields[3])
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%s: releases = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"io"
+	"time"
+	"log"
+	"fmt"
+	"sort"
+	"strings"
+	"path/filepath"
+	"io/ioutil"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"grm/version"
+)
+
+// Common interface of text/template and html/template templates
+type reportTemplateRenderer interface {
+	Execute(writer io.Writer, data interface{}) error
+}
+
+type releaseGroup struct {
+	Key      string
+	Releases []*reportRelease
+}
+
+var reportTemplateFunctions = map[string]interface{}{
+	"formatDate": templateFormatDate,
+	"releases":   templateReleases,
+	"sortBy":     templateSortBy,
+	"reverse":    templateReverse,
+	"groupBy":    templateGroupBy,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"join":       strings.Join,
+}
+
+func loadReportTemplate(path string) reportTemplateRenderer {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("Could not read report template '%s': ", path), err)
+	}
+
+	name := filepath.Base(path)
+	extension := strings.ToLower(filepath.Ext(path))
+
+	if extension == ".html" || extension == ".htm" {
+		tmpl, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(reportTemplateFunctions)).Parse(string(data))
+		if err != nil {
+			log.Fatal(fmt.Sprintf("Could not parse report template '%s': ", path), err)
+		}
+		return tmpl
+	}
+
+	tmpl, err := texttemplate.New(name).Funcs(texttemplate.FuncMap(reportTemplateFunctions)).Parse(string(data))
+	if err != nil {
+		log.Fatal(fmt.Sprintf("Could not parse report template '%s': ", path), err)
+	}
+	return tmpl
+}
+
+func printTemplateReport(writer io.Writer, tmpl reportTemplateRenderer, definitions []string, dates dateRange, repositories []*repository) {
+	document := buildReportDocument(definitions, dates, repositories)
+	if err := tmpl.Execute(writer, document); err != nil {
+		log.Fatal("Could not render report template: ", err)
+	}
+}
+
+func templateFormatDate(layout string, date time.Time) string {
+	return date.Format(layout)
+}
+
+// Flattens the releases of all given repositories into a single list
+func templateReleases(repositories []*reportRepository) []*reportRelease {
+	releases := make([]*reportRelease, 0)
+	for _, rep := range repositories {
+		releases = append(releases, rep.Releases...)
+	}
+	return releases
+}
+
+func templateSortBy(property string, releases []*reportRelease) ([]*reportRelease, error) {
+	var less func(a, b *reportRelease) bool
+	switch property {
+	case "name":
+		less = func(a, b *reportRelease) bool { return a.Name < b.Name }
+	case "tag":
+		less = func(a, b *reportRelease) bool { return a.Tag < b.Tag }
+	case "repository":
+		less = func(a, b *reportRelease) bool { return a.Repository < b.Repository }
+	case "created":
+		less = func(a, b *reportRelease) bool { return a.Created.Before(b.Created) }
+	case "version":
+		less = func(a, b *reportRelease) bool {
+			if a.version == nil || b.version == nil {
+				return a.version == nil && b.version != nil
+			}
+			return version.Compare(a.version, b.version) < 0
+		}
+	default:
+		return nil, fmt.Errorf("unknown sort property: %s", property)
+	}
+
+	sorted := make([]*reportRelease, len(releases))
+	copy(sorted, releases)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return sorted, nil
+}
+
+func templateReverse(releases []*reportRelease) []*reportRelease {
+	reversed := make([]*reportRelease, len(releases))
+	for i, rel := range releases {
+		reversed[len(releases)-1-i] = rel
+	}
+	return reversed
+}
+
+// Groups releases by the given property, groups keep the order of their first release
+func templateGroupBy(property string, releases []*reportRelease) ([]*releaseGroup, error) {
+	var keyOf func(rel *reportRelease) string
+	switch property {
+	case "repository":
+		keyOf = func(rel *reportRelease) string { return rel.Repository }
+	case "definition":
+		keyOf = func(rel *reportRelease) string { return rel.Definition }
+	case "bump":
+		keyOf = func(rel *reportRelease) string { return rel.Bump }
+	case "year":
+		keyOf = func(rel *reportRelease) string { return rel.Created.Format("2006") }
+	case "month":
+		keyOf = func(rel *reportRelease) string { return rel.Created.Format("2006-01") }
+	case "week":
+		keyOf = func(rel *reportRelease) string {
+			year, week := rel.Created.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}
+	case "day":
+		keyOf = func(rel *reportRelease) string { return rel.Created.Format("2006-01-02") }
+	default:
+		return nil, fmt.Errorf("unknown group property: %s", property)
+	}
+
+	groups := make([]*releaseGroup, 0)
+	lookup := make(map[string]*releaseGroup)
+	for _, rel := range releases {
+		key := keyOf(rel)
+		group, ok := lookup[key]
+		if !ok {
+			group = &releaseGroup{Key: key}
+			lookup[key] = group
+			groups = append(groups, group)
+		}
+		group.Releases = append(group.Releases, rel)
+	}
+	return groups, nil
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"net/http"
+	"grm/config"
+	"fmt"
+	"strings"
+	"encoding/json"
+	"encoding/xml"
+	"net/url"
+	"time"
+	"io"
+	"io/ioutil"
+	"grm/version"
+)
+
+// Public registries used unless the remote definition configures a mirror or stand-in
+const (
+	defaultMavenUrl  = "https://repo1.maven.org/maven2"
+	defaultNpmUrl    = "https://registry.npmjs.org"
+	defaultPypiUrl   = "https://pypi.org"
+	defaultNugetUrl  = "https://api.nuget.org/v3-flatcontainer"
+	defaultCratesUrl = "https://crates.io"
+)
+
+// The download of a release, the page url links to the human readable page of the package version
+type download struct {
+	url     string
+	pageUrl string
+}
+
+// The release a download is resolved for
+type downloadTarget struct {
+	account       string
+	repository    string
+	version       string
+	tag           string
+	milestone     string
+	date          time.Time
+	parsedVersion *version.Version
+}
+
+// An additional download of a release, e.g. the documentation or javadoc
+type downloadLink struct {
+	name         string
+	url          string
+	verification *verification
+}
+
+type namedTemplate struct {
+	name     string
+	template *downloadTemplate
+}
+
+// Resolves the download of a release, a nil download means the release has no download (yet)
+type downloadResolver interface {
+	resolve(target *downloadTarget) (*download, error)
+}
+
+// Creates the download resolvers of a remote definition, all resolvers share the http client
+// and registry urls of the definition
+type downloadResolvers struct {
+	name       string
+	httpClient *http.Client
+	mavenUrl   string
+	npmUrl     string
+	pypiUrl    string
+	nugetUrl   string
+	cratesUrl  string
+}
+
+func newDownloadResolvers(name string) (*downloadResolvers, error) {
+	transport, err := newBaseTransport(name)
+	if err != nil {
+		return nil, err
+	}
+	return &downloadResolvers{
+		name: name,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+		},
+		mavenUrl:  readRegistryUrl(name, config.MavenUrl, defaultMavenUrl),
+		npmUrl:    readRegistryUrl(name, config.NpmUrl, defaultNpmUrl),
+		pypiUrl:   readRegistryUrl(name, config.PypiUrl, defaultPypiUrl),
+		nugetUrl:  readRegistryUrl(name, config.NugetUrl, defaultNugetUrl),
+		cratesUrl: readRegistryUrl(name, config.CratesUrl, defaultCratesUrl),
+	}, nil
+}
+
+// Selects the resolver of a repository using the download-resolver property, e.g. "npm:left-pad" or
+// "maven:com.example:example-core". Without a download-url template, the template resolver returns nil.
+func (r *downloadResolvers) forRepository(repository string) (downloadResolver, error) {
+	resolver := "template"
+	if s, ok := configuration.NamedSectionGet(r.name, config.Remote, config.DownloadResolver, repository); ok && s != "" {
+		resolver = s
+	}
+
+	parts := strings.Split(resolver, ":")
+	for _, part := range parts[1:] {
+		if part == "" {
+			return nil, fmt.Errorf("invalid download resolver: %s", resolver)
+		}
+	}
+
+	switch {
+	case parts[0] == "template" && len(parts) == 1:
+		downloadUrl, _ := configuration.NamedSectionGet(r.name, config.Remote, config.DownloadUrl, repository)
+		if downloadUrl == "" {
+			return nil, nil
+		}
+		template, err := parseDownloadTemplate(downloadUrl)
+		if err != nil {
+			return nil, err
+		}
+		return &templateResolver{template: template}, nil
+	case parts[0] == "maven" && len(parts) == 3:
+		return &mavenResolver{httpClient: r.httpClient, baseUrl: r.mavenUrl, groupId: parts[1], artifactId: parts[2]}, nil
+	case parts[0] == "npm" && len(parts) == 2:
+		return &npmResolver{httpClient: r.httpClient, baseUrl: r.npmUrl, pkg: parts[1]}, nil
+	case parts[0] == "pypi" && len(parts) == 2:
+		return &pypiResolver{httpClient: r.httpClient, baseUrl: r.pypiUrl, pkg: parts[1]}, nil
+	case parts[0] == "nuget" && len(parts) == 2:
+		return &nugetResolver{httpClient: r.httpClient, baseUrl: r.nugetUrl, pkg: parts[1]}, nil
+	case parts[0] == "crates" && len(parts) == 2:
+		return &cratesResolver{httpClient: r.httpClient, baseUrl: r.cratesUrl, crate: parts[1]}, nil
+	}
+	return nil, fmt.Errorf("unknown download resolver: %s", resolver)
+}
+
+// Reads the named download links of a repository, e.g. "docs=https://example.com/{version}/ javadoc=..."
+func (r *downloadResolvers) linksForRepository(repository string) ([]*namedTemplate, error) {
+	value, ok := configuration.NamedSectionGet(r.name, config.Remote, config.DownloadLinks, repository)
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	links := make([]*namedTemplate, 0)
+	for _, link := range strings.Fields(value) {
+		parts := strings.SplitN(link, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid download link: %s", link)
+		}
+		template, err := parseDownloadTemplate(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, &namedTemplate{name: parts[0], template: template})
+	}
+	return links, nil
+}
+
+func readRegistryUrl(name string, key config.Key, defaultUrl string) string {
+	registryUrl := defaultUrl
+	if u, ok := configuration.NamedSectionGet(name, config.Remote, key, ""); ok && u != "" {
+		registryUrl = u
+	}
+	return strings.TrimSuffix(registryUrl, "/")
+}
+
+// Renders the download-url template, the url is checked by the download verification
+type templateResolver struct {
+	template *downloadTemplate
+}
+
+func (r *templateResolver) resolve(target *downloadTarget) (*download, error) {
+	return &download{url: r.template.render(target)}, nil
+}
+
+// Looks up the version in the maven-metadata.xml of the artifact
+type mavenResolver struct {
+	httpClient *http.Client
+	baseUrl    string
+	groupId    string
+	artifactId string
+}
+
+type mavenMetadata struct {
+	Versions []string `xml:"versioning>versions>version"`
+}
+
+func (r *mavenResolver) resolve(target *downloadTarget) (*download, error) {
+	version := registryVersion(target.version)
+	artifactUrl := fmt.Sprintf("%s/%s/%s", r.baseUrl, strings.Replace(r.groupId, ".", "/", -1), r.artifactId)
+
+	metadata := &mavenMetadata{}
+	found, err := readRegistryDocument(r.httpClient, artifactUrl+"/maven-metadata.xml", func(body io.Reader) error {
+		return xml.NewDecoder(body).Decode(metadata)
+	})
+	if err != nil || !found || !containsString(metadata.Versions, version) {
+		return nil, err
+	}
+
+	return &download{
+		url:     fmt.Sprintf("%s/%s/%s-%s.jar", artifactUrl, version, r.artifactId, version),
+		pageUrl: fmt.Sprintf("https://central.sonatype.com/artifact/%s/%s/%s", r.groupId, r.artifactId, version),
+	}, nil
+}
+
+// Looks up the version in the package document of the npm registry, scoped packages included
+type npmResolver struct {
+	httpClient *http.Client
+	baseUrl    string
+	pkg        string
+}
+
+type npmPackage struct {
+	Versions map[string]struct {
+		Dist struct {
+			Tarball string `json:"tarball"`
+		} `json:"dist"`
+	} `json:"versions"`
+}
+
+func (r *npmResolver) resolve(target *downloadTarget) (*download, error) {
+	version := registryVersion(target.version)
+
+	document := &npmPackage{}
+	found, err := readRegistryDocument(r.httpClient, fmt.Sprintf("%s/%s", r.baseUrl, url.PathEscape(r.pkg)), func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(document)
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+
+	v, ok := document.Versions[version]
+	if !ok || v.Dist.Tarball == "" {
+		return nil, nil
+	}
+	return &download{
+		url:     v.Dist.Tarball,
+		pageUrl: fmt.Sprintf("https://www.npmjs.com/package/%s/v/%s", r.pkg, version),
+	}, nil
+}
+
+// Looks up the version using the JSON API of PyPI, source distributions are preferred over wheels
+type pypiResolver struct {
+	httpClient *http.Client
+	baseUrl    string
+	pkg        string
+}
+
+type pypiRelease struct {
+	Info struct {
+		ReleaseUrl string `json:"release_url"`
+	} `json:"info"`
+	Urls []struct {
+		Url         string `json:"url"`
+		PackageType string `json:"packagetype"`
+	} `json:"urls"`
+}
+
+func (r *pypiResolver) resolve(target *downloadTarget) (*download, error) {
+	version := registryVersion(target.version)
+
+	document := &pypiRelease{}
+	found, err := readRegistryDocument(r.httpClient, fmt.Sprintf("%s/pypi/%s/%s/json", r.baseUrl, url.PathEscape(r.pkg), version), func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(document)
+	})
+	if err != nil || !found || len(document.Urls) == 0 {
+		return nil, err
+	}
+
+	downloadUrl := document.Urls[0].Url
+	for _, u := range document.Urls {
+		if u.PackageType == "sdist" {
+			downloadUrl = u.Url
+		}
+	}
+
+	pageUrl := document.Info.ReleaseUrl
+	if pageUrl == "" {
+		pageUrl = fmt.Sprintf("https://pypi.org/project/%s/%s/", r.pkg, version)
+	}
+	return &download{url: downloadUrl, pageUrl: pageUrl}, nil
+}
+
+// Looks up the version in the package index of the NuGet flat container, ids and versions are lower case
+type nugetResolver struct {
+	httpClient *http.Client
+	baseUrl    string
+	pkg        string
+}
+
+type nugetIndex struct {
+	Versions []string `json:"versions"`
+}
+
+func (r *nugetResolver) resolve(target *downloadTarget) (*download, error) {
+	version := strings.ToLower(registryVersion(target.version))
+	id := strings.ToLower(r.pkg)
+
+	index := &nugetIndex{}
+	found, err := readRegistryDocument(r.httpClient, fmt.Sprintf("%s/%s/index.json", r.baseUrl, id), func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(index)
+	})
+	if err != nil || !found || !containsString(index.Versions, version) {
+		return nil, err
+	}
+
+	return &download{
+		url:     fmt.Sprintf("%s/%s/%s/%s.%s.nupkg", r.baseUrl, id, version, id, version),
+		pageUrl: fmt.Sprintf("https://www.nuget.org/packages/%s/%s", r.pkg, version),
+	}, nil
+}
+
+// Looks up the version using the crates.io API, yanked versions have no download
+type cratesResolver struct {
+	httpClient *http.Client
+	baseUrl    string
+	crate      string
+}
+
+type cratesVersion struct {
+	Version struct {
+		DownloadPath string `json:"dl_path"`
+		Yanked       bool   `json:"yanked"`
+	} `json:"version"`
+}
+
+func (r *cratesResolver) resolve(target *downloadTarget) (*download, error) {
+	version := registryVersion(target.version)
+
+	document := &cratesVersion{}
+	found, err := readRegistryDocument(r.httpClient, fmt.Sprintf("%s/api/v1/crates/%s/%s", r.baseUrl, url.PathEscape(r.crate), version), func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(document)
+	})
+	if err != nil || !found || document.Version.Yanked || document.Version.DownloadPath == "" {
+		return nil, err
+	}
+
+	return &download{
+		url:     r.baseUrl + document.Version.DownloadPath,
+		pageUrl: fmt.Sprintf("https://crates.io/crates/%s/%s", r.crate, version),
+	}, nil
+}
+
+// Reads a metadata document of a registry, a missing document (404) is not an error but
+// tells that the package or version doesn't exist
+func readRegistryDocument(httpClient *http.Client, documentUrl string, decode func(body io.Reader) error) (bool, error) {
+	request, err := http.NewRequest("GET", documentUrl, nil)
+	if err != nil {
+		return false, err
+	}
+	// crates.io rejects requests without user agent
+	request.Header.Set("User-Agent", "github-release-monitor")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return false, fmt.Errorf("cannot read registry metadata %s: %v", documentUrl, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		io.Copy(ioutil.Discard, response.Body)
+		return false, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("cannot read registry metadata %s: %s", documentUrl, response.Status)
+	}
+
+	if err := decode(response.Body); err != nil {
+		return false, fmt.Errorf("cannot parse registry metadata %s: %v", documentUrl, err)
+	}
+	return true, nil
+}
+
+// Package registries don't use the "v" prefix common for tags
+func registryVersion(version string) string {
+	if len(version) > 1 && (version[0] == 'v' || version[0] == 'V') && version[1] >= '0' && version[1] <= '9' {
+		return version[1:]
+	}
+	return version
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
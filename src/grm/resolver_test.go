@@ -0,0 +1,128 @@
+package main
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"fmt"
+	"io"
+)
+
+// Serves registry documents by escaped path, unknown paths are missing
+func newTestRegistry(documents map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.EscapedPath() == "/broken" {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		document, ok := documents[request.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(writer, request)
+			return
+		}
+		fmt.Fprint(writer, document)
+	}))
+}
+
+func TestDownloadResolvers(t *testing.T) {
+	server := newTestRegistry(map[string]string{
+		"/maven/com/example/example-core/maven-metadata.xml": "<metadata><versioning><versions>" +
+			"<version>1.2.2</version><version>1.2.3</version></versions></versioning></metadata>",
+		"/npm/@example%2Ftool": `{"versions": {"1.2.3": {"dist": {"tarball": "https://registry.example.com/tool-1.2.3.tgz"}}}}`,
+		"/pypi/pypi/example/1.2.3/json": `{"info": {"release_url": "https://pypi.org/project/example/1.2.3/"}, "urls": [` +
+			`{"url": "https://files.example.com/example-1.2.3-py3-none-any.whl", "packagetype": "bdist_wheel"},` +
+			`{"url": "https://files.example.com/example-1.2.3.tar.gz", "packagetype": "sdist"}]}`,
+		"/pypi/pypi/wheels/1.2.3/json": `{"info": {}, "urls": [` +
+			`{"url": "https://files.example.com/wheels-1.2.3-py3-none-any.whl", "packagetype": "bdist_wheel"}]}`,
+		"/pypi/pypi/with%2Fslash/1.2.3/json":  `{"info": {}, "urls": [{"url": "https://files.example.com/escaped.tar.gz"}]}`,
+		"/nuget/example.core/index.json":      `{"versions": ["1.2.2", "1.2.3-rc1"]}`,
+		"/crates/api/v1/crates/example/1.2.3": `{"version": {"dl_path": "/api/v1/crates/example/1.2.3/download", "yanked": false}}`,
+		"/crates/api/v1/crates/example/1.2.4": `{"version": {"dl_path": "/api/v1/crates/example/1.2.4/download", "yanked": true}}`,
+	})
+	defer server.Close()
+	client := server.Client()
+
+	tests := []struct {
+		name     string
+		resolver downloadResolver
+		version  string
+		url      string
+		pageUrl  string
+		err      bool
+	}{
+		{"maven", &mavenResolver{httpClient: client, baseUrl: server.URL + "/maven", groupId: "com.example", artifactId: "example-core"},
+			"v1.2.3", server.URL + "/maven/com/example/example-core/1.2.3/example-core-1.2.3.jar",
+			"https://central.sonatype.com/artifact/com.example/example-core/1.2.3", false},
+		{"maven unknown version", &mavenResolver{httpClient: client, baseUrl: server.URL + "/maven", groupId: "com.example", artifactId: "example-core"},
+			"1.3.0", "", "", false},
+		{"maven unknown artifact", &mavenResolver{httpClient: client, baseUrl: server.URL + "/maven", groupId: "com.example", artifactId: "other"},
+			"1.2.3", "", "", false},
+		{"npm scoped package", &npmResolver{httpClient: client, baseUrl: server.URL + "/npm", pkg: "@example/tool"},
+			"1.2.3", "https://registry.example.com/tool-1.2.3.tgz", "https://www.npmjs.com/package/@example/tool/v/1.2.3", false},
+		{"npm unknown version", &npmResolver{httpClient: client, baseUrl: server.URL + "/npm", pkg: "@example/tool"},
+			"1.2.4", "", "", false},
+		{"pypi prefers sdist", &pypiResolver{httpClient: client, baseUrl: server.URL + "/pypi", pkg: "example"},
+			"1.2.3", "https://files.example.com/example-1.2.3.tar.gz", "https://pypi.org/project/example/1.2.3/", false},
+		{"pypi without sdist", &pypiResolver{httpClient: client, baseUrl: server.URL + "/pypi", pkg: "wheels"},
+			"1.2.3", "https://files.example.com/wheels-1.2.3-py3-none-any.whl", "https://pypi.org/project/wheels/1.2.3/", false},
+		{"pypi escaped package", &pypiResolver{httpClient: client, baseUrl: server.URL + "/pypi", pkg: "with/slash"},
+			"1.2.3", "https://files.example.com/escaped.tar.gz", "https://pypi.org/project/with/slash/1.2.3/", false},
+		{"pypi unknown version", &pypiResolver{httpClient: client, baseUrl: server.URL + "/pypi", pkg: "example"},
+			"1.2.4", "", "", false},
+		{"nuget lower cases id and version", &nugetResolver{httpClient: client, baseUrl: server.URL + "/nuget", pkg: "Example.Core"},
+			"1.2.3-RC1", server.URL + "/nuget/example.core/1.2.3-rc1/example.core.1.2.3-rc1.nupkg",
+			"https://www.nuget.org/packages/Example.Core/1.2.3-rc1", false},
+		{"nuget unknown version", &nugetResolver{httpClient: client, baseUrl: server.URL + "/nuget", pkg: "Example.Core"},
+			"1.2.4", "", "", false},
+		{"crates", &cratesResolver{httpClient: client, baseUrl: server.URL + "/crates", crate: "example"},
+			"1.2.3", server.URL + "/crates/api/v1/crates/example/1.2.3/download", "https://crates.io/crates/example/1.2.3", false},
+		{"crates yanked version", &cratesResolver{httpClient: client, baseUrl: server.URL + "/crates", crate: "example"},
+			"1.2.4", "", "", false},
+		{"registry failure", &npmResolver{httpClient: client, baseUrl: server.URL, pkg: "broken"},
+			"1.2.3", "", "", true},
+	}
+
+	for _, test := range tests {
+		d, err := test.resolver.resolve(&downloadTarget{version: test.version})
+		if (err != nil) != test.err {
+			t.Errorf("%s: error = %v, want error %v", test.name, err, test.err)
+			continue
+		}
+		if test.url == "" {
+			if d != nil {
+				t.Errorf("%s: download %+v, want none", test.name, d)
+			}
+			continue
+		}
+		if d == nil || d.url != test.url || d.pageUrl != test.pageUrl {
+			t.Errorf("%s: download = %+v, want %s %s", test.name, d, test.url, test.pageUrl)
+		}
+	}
+}
+
+func TestReadRegistryDocument(t *testing.T) {
+	server := newTestRegistry(map[string]string{"/document": "{}"})
+	defer server.Close()
+
+	tests := []struct {
+		path  string
+		found bool
+		err   bool
+	}{
+		{"/document", true, false},
+		// Missing packages or versions have no download, but aren't an error
+		{"/missing", false, false},
+		{"/broken", false, true},
+	}
+
+	for _, test := range tests {
+		decoded := false
+		found, err := readRegistryDocument(server.Client(), server.URL+test.path, func(body io.Reader) error {
+			decoded = true
+			return nil
+		})
+		if found != test.found || (err != nil) != test.err || decoded != test.found {
+			t.Errorf("%s: found = %v, %v, decoded %v, want %v, error %v", test.path, found, err, decoded, test.found, test.err)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"time"
+	"strings"
+	"fmt"
+	"strconv"
+)
+
+// Shortcuts of common schedules
+var scheduleShortcuts = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// Bounds of the five cron fields: minute, hour, day of month, month and day of week
+var scheduleFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// A cron-style schedule, either five fields (minute hour day-of-month month day-of-week) or a
+// fixed interval like "@every 30m". Times are evaluated in the location of the schedule.
+type schedule struct {
+	expression string
+	every      time.Duration
+	fields     [5]map[int]bool
+	// Like cron, day of month and day of week match alternatively if both are restricted
+	anyDay   bool
+	location *time.Location
+}
+
+func parseSchedule(expression string, location *time.Location) (*schedule, error) {
+	expression = strings.TrimSpace(expression)
+	s := &schedule{expression: expression, location: location}
+
+	if strings.HasPrefix(expression, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expression, "@every ")))
+		if err != nil || every < time.Minute {
+			return nil, fmt.Errorf("invalid schedule interval, at least 1m required: %s", expression)
+		}
+		s.every = every
+		return s, nil
+	}
+
+	if shortcut, ok := scheduleShortcuts[expression]; ok {
+		expression = shortcut
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != len(scheduleFields) {
+		return nil, fmt.Errorf("invalid schedule, 5 fields expected: %s", expression)
+	}
+
+	for i, field := range fields {
+		values, err := parseScheduleField(field, scheduleFields[i].min, scheduleFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in schedule %s: %v", scheduleFields[i].name, expression, err)
+		}
+		s.fields[i] = values
+	}
+
+	// Sunday is both 0 and 7
+	if s.fields[4][7] {
+		s.fields[4][0] = true
+	}
+	s.anyDay = fields[2] != "*" && fields[4] != "*"
+
+	// Days not existing in the given months, e.g. "0 0 31 2 *", never match
+	if s.next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule never matches: %s", expression)
+	}
+	return s, nil
+}
+
+// Parses a list of values, ranges and steps, e.g. "*/15", "1-5" or "0,30"
+func parseScheduleField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i != -1 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s < 1 {
+				return nil, fmt.Errorf("invalid step: %s", part)
+			}
+			step = s
+			part = part[:i]
+		}
+
+		start, end := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			s, err1 := strconv.Atoi(bounds[0])
+			e, err2 := strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid range: %s", part)
+			}
+			start, end = s, e
+		default:
+			v, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value: %s", part)
+			}
+			start, end = v, v
+			if step > 1 {
+				end = max
+			}
+		}
+
+		// Day of week allows 7 as an alias of sunday
+		upper := max
+		if max == 6 {
+			upper = 7
+		}
+		if start < min || end > upper || start > end {
+			return nil, fmt.Errorf("value out of range: %s", part)
+		}
+
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// Calculates the first time after the given time matching the schedule
+func (s *schedule) next(after time.Time) time.Time {
+	if s.every > 0 {
+		return after.Add(s.every)
+	}
+
+	t := after.In(s.location).Truncate(time.Minute).Add(time.Minute)
+
+	// Every matching time repeats at least once within a few years (e.g. February 29th)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !s.fields[3][int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+			continue
+		}
+		if !s.fields[1][t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+			continue
+		}
+		if !s.fields[0][t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *schedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.fields[2][t.Day()]
+	dayOfWeek := s.fields[4][int(t.Weekday())]
+	if s.anyDay {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+func (s *schedule) String() string {
+	return s.expression
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+
+	tests := []struct {
+		expression string
+		location   *time.Location
+		want       time.Time
+	}{
+		{"@hourly", time.UTC, time.Date(2018, 5, 16, 16, 0, 0, 0, time.UTC)},
+		{"@daily", time.UTC, time.Date(2018, 5, 17, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.UTC, time.Date(2018, 5, 20, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.UTC, time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.UTC, time.Date(2018, 5, 16, 15, 45, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.UTC, time.Date(2018, 5, 17, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 6,7", time.UTC, time.Date(2018, 5, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.UTC, time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Day of month and day of week match alternatively if both are restricted
+		{"0 0 1 * 7", time.UTC, time.Date(2018, 5, 20, 0, 0, 0, 0, time.UTC)},
+		{"30 8 15 * 3", time.UTC, time.Date(2018, 5, 23, 8, 30, 0, 0, time.UTC)},
+		{"0 18 * * *", berlin, time.Date(2018, 5, 16, 18, 0, 0, 0, berlin)},
+		{"@every 90m", time.UTC, testNow.Add(90 * time.Minute)},
+	}
+
+	for _, test := range tests {
+		s, err := parseSchedule(test.expression, test.location)
+		if err != nil {
+			t.Errorf("%q: %v", test.expression, err)
+			continue
+		}
+		if next := s.next(testNow); !next.Equal(test.want) {
+			t.Errorf("%q: next = %s, want %s", test.expression, next, test.want)
+		}
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"@yearly",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"@every 30s",
+		"@every soon",
+		// Days not existing in the month never match
+		"0 0 31 2 *",
+		"0 0 31 4,6,9,11 *",
+	} {
+		if _, err := parseSchedule(expression, time.UTC); err == nil {
+			t.Errorf("%q: parsed, want error", expression)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package state
+
+import (
+	"path/filepath"
+	"os"
+	"fmt"
+	"io/ioutil"
+	"encoding/json"
+	"time"
+	"net/url"
+)
+
+// Version of the state file, must be increased on any incompatible change
+const stateVersion = 1
+
+type Store interface {
+	Seen(repository, tag string) bool
+	Runs() []*Run
+	Mark(releases []*Release) error
+	Rollback(runs int) (int, error)
+	Reset() error
+	// Tells if the existing releases were recorded once, even if there were none to mark
+	Initialized() bool
+	Initialize() error
+}
+
+type Release struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+}
+
+type Run struct {
+	Id       int        `json:"id"`
+	Marked   time.Time  `json:"marked"`
+	Releases []*Release `json:"releases"`
+}
+
+type store struct {
+	path  string
+	state *state
+	seen  map[string]bool
+}
+
+type state struct {
+	Version     int    `json:"version"`
+	Initialized bool   `json:"initialized,omitempty"`
+	Runs        []*Run `json:"runs"`
+}
+
+// Reads the state of a remote definition. Namespaces keep independent states of the same
+// definition apart, the report command uses the empty namespace. The name is escaped, so
+// that names containing path separators can't escape the state directory.
+func NewStore(homeDir, namespace, name string) (Store, error) {
+	statePath := filepath.Join(homeDir, "github-release-monitor", "state", url.PathEscape(namespace),
+		fmt.Sprintf("%s.json", url.PathEscape(name)))
+
+	store := &store{
+		path:  statePath,
+		state: &state{Version: stateVersion, Runs: make([]*Run, 0)},
+	}
+
+	if data, err := ioutil.ReadFile(statePath); err == nil {
+		if err := json.Unmarshal(data, store.state); err != nil {
+			return nil, fmt.Errorf("could not read state file '%s': %v", statePath, err)
+		}
+		if store.state.Version > stateVersion {
+			return nil, fmt.Errorf("state file '%s' was written by a newer version", statePath)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not read state file '%s': %v", statePath, err)
+	}
+
+	store.index()
+	return store, nil
+}
+
+func (s *store) Seen(repository, tag string) bool {
+	return s.seen[buildKey(repository, tag)]
+}
+
+func (s *store) Runs() []*Run {
+	return s.state.Runs
+}
+
+// Marks the given releases as seen, releases already seen are ignored. All
+// releases marked at once are recorded as a single run for later rollback.
+func (s *store) Mark(releases []*Release) error {
+	unseen := make([]*Release, 0, len(releases))
+	for _, release := range releases {
+		key := buildKey(release.Repository, release.Tag)
+		if !s.seen[key] {
+			s.seen[key] = true
+			unseen = append(unseen, release)
+		}
+	}
+
+	if len(unseen) == 0 {
+		return nil
+	}
+
+	id := 1
+	if len(s.state.Runs) > 0 {
+		id = s.state.Runs[len(s.state.Runs)-1].Id + 1
+	}
+
+	s.state.Runs = append(s.state.Runs, &Run{
+		Id:       id,
+		Marked:   time.Now().UTC(),
+		Releases: unseen,
+	})
+	return s.store()
+}
+
+// Removes the given number of most recent runs and returns the number of removed runs
+func (s *store) Rollback(runs int) (int, error) {
+	if runs > len(s.state.Runs) {
+		runs = len(s.state.Runs)
+	}
+	if runs <= 0 {
+		return 0, nil
+	}
+
+	s.state.Runs = s.state.Runs[:len(s.state.Runs)-runs]
+	s.index()
+	return runs, s.store()
+}
+
+func (s *store) Reset() error {
+	s.state.Runs = make([]*Run, 0)
+	s.state.Initialized = false
+	s.index()
+
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not delete state file '%s': %v", s.path, err)
+	}
+	return nil
+}
+
+// Marked runs don't initialize the state, they may only cover part of the existing releases
+func (s *store) Initialized() bool {
+	return s.state.Initialized
+}
+
+func (s *store) Initialize() error {
+	if s.state.Initialized {
+		return nil
+	}
+	s.state.Initialized = true
+	return s.store()
+}
+
+func (s *store) index() {
+	s.seen = make(map[string]bool)
+	for _, run := range s.state.Runs {
+		for _, release := range run.Releases {
+			s.seen[buildKey(release.Repository, release.Tag)] = true
+		}
+	}
+}
+
+func (s *store) store() error {
+	statePath := filepath.Dir(s.path)
+	if err := os.MkdirAll(statePath, os.ModePerm); err != nil {
+		return fmt.Errorf("could not create state directory '%s': %v", statePath, err)
+	}
+
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not serialize state: %v", err)
+	}
+
+	// Write to a temporary file first to never leave a half written state behind
+	tempPath := s.path + ".tmp"
+	if err := ioutil.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("could not write state file '%s': %v", tempPath, err)
+	}
+	if err := os.Rename(tempPath, s.path); err != nil {
+		return fmt.Errorf("could not write state file '%s': %v", s.path, err)
+	}
+	return nil
+}
+
+func buildKey(repository, tag string) string {
+	return fmt.Sprintf("%s@%s", repository, tag)
+}
@@ -0,0 +1,220 @@
+package state
+
+import (
+	"testing"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func newTestStore(t *testing.T, home string) Store {
+	store, err := NewStore(home, "", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func testReleases(tags ...string) []*Release {
+	releases := make([]*Release, 0, len(tags))
+	for _, tag := range tags {
+		releases = append(releases, &Release{Repository: "noctarius/example", Tag: tag})
+	}
+	return releases
+}
+
+func TestMark(t *testing.T) {
+	home, err := ioutil.TempDir("", "grm-state-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	store := newTestStore(t, home)
+	if err := store.Mark(testReleases("v1.0.0", "v1.1.0")); err != nil {
+		t.Fatal(err)
+	}
+	// Releases already seen don't start a new run, nor do runs without releases
+	if err := store.Mark(testReleases("v1.1.0", "v1.2.0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Mark(testReleases("v1.2.0")); err != nil {
+		t.Fatal(err)
+	}
+
+	reread := newTestStore(t, home)
+	runs := reread.Runs()
+	if len(runs) != 2 || runs[0].Id != 1 || len(runs[0].Releases) != 2 || runs[1].Id != 2 || len(runs[1].Releases) != 1 {
+		t.Fatalf("runs = %+v, want 2 runs of 2 and 1 releases", runs)
+	}
+	for _, tag := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		if !reread.Seen("noctarius/example", tag) {
+			t.Errorf("%s not seen", tag)
+		}
+	}
+	if reread.Seen("noctarius/example", "v2.0.0") || reread.Seen("noctarius/other", "v1.0.0") {
+		t.Error("unmarked release seen")
+	}
+}
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		runs    int
+		removed int
+		seen    []string
+		unseen  []string
+	}{
+		{0, 0, []string{"v1.0.0", "v1.1.0", "v1.2.0"}, nil},
+		{1, 1, []string{"v1.0.0", "v1.1.0"}, []string{"v1.2.0"}},
+		{2, 2, []string{"v1.0.0"}, []string{"v1.1.0", "v1.2.0"}},
+		{5, 3, nil, []string{"v1.0.0", "v1.1.0", "v1.2.0"}},
+	}
+
+	for _, test := range tests {
+		home, err := ioutil.TempDir("", "grm-state-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		store := newTestStore(t, home)
+		for _, tag := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+			if err := store.Mark(testReleases(tag)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if removed, err := store.Rollback(test.runs); err != nil || removed != test.removed {
+			t.Errorf("rollback %d: removed = %d, %v, want %d", test.runs, removed, err, test.removed)
+		}
+		for _, s := range []Store{store, newTestStore(t, home)} {
+			for _, tag := range test.seen {
+				if !s.Seen("noctarius/example", tag) {
+					t.Errorf("rollback %d: %s not seen", test.runs, tag)
+				}
+			}
+			for _, tag := range test.unseen {
+				if s.Seen("noctarius/example", tag) {
+					t.Errorf("rollback %d: %s still seen", test.runs, tag)
+				}
+			}
+		}
+		os.RemoveAll(home)
+	}
+}
+
+func TestReset(t *testing.T) {
+	home, err := ioutil.TempDir("", "grm-state-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	store := newTestStore(t, home)
+	// Resetting a state never written is fine
+	if err := store.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Mark(testReleases("v1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.Seen("noctarius/example", "v1.0.0") || len(newTestStore(t, home).Runs()) != 0 {
+		t.Error("release still seen after reset")
+	}
+}
+
+func TestNewStorePaths(t *testing.T) {
+	home, err := ioutil.TempDir("", "grm-state-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	stateDir := filepath.Join(home, "github-release-monitor", "state")
+
+	tests := []struct {
+		namespace string
+		name      string
+		path      string
+	}{
+		{"", "foo", filepath.Join(stateDir, "foo.json")},
+		{"daemon", "foo", filepath.Join(stateDir, "daemon", "foo.json")},
+		// Names never leave the state directory
+		{"", "../../foo", filepath.Join(stateDir, "..%2F..%2Ffoo.json")},
+		{"", "foo/bar", filepath.Join(stateDir, "foo%2Fbar.json")},
+		{"daemon", "..", filepath.Join(stateDir, "daemon", "...json")},
+	}
+
+	for _, test := range tests {
+		store, err := NewStore(home, test.namespace, test.name)
+		if err != nil {
+			t.Errorf("%q: %v", test.name, err)
+			continue
+		}
+		if err := store.Mark(testReleases("v1.0.0")); err != nil {
+			t.Errorf("%q: %v", test.name, err)
+			continue
+		}
+		if _, err := os.Stat(test.path); err != nil {
+			t.Errorf("%q: state not written to %s: %v", test.name, test.path, err)
+		}
+	}
+}
+
+func TestNewStoreInvalid(t *testing.T) {
+	home, err := ioutil.TempDir("", "grm-state-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	stateDir := filepath.Join(home, "github-release-monitor", "state")
+	os.MkdirAll(stateDir, os.ModePerm)
+
+	for name, content := range map[string]string{
+		"corrupt": "{",
+		"newer":   `{"version": 2, "runs": []}`,
+	} {
+		ioutil.WriteFile(filepath.Join(stateDir, name+".json"), []byte(content), 0600)
+		if _, err := NewStore(home, "", name); err == nil {
+			t.Errorf("%s: read, want error", name)
+		}
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	home, err := ioutil.TempDir("", "grm-state-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	store := newTestStore(t, home)
+	if err := store.Mark(testReleases("v1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	if store.Initialized() {
+		t.Error("initialized by marked releases")
+	}
+
+	// Initialized states stay without any runs
+	store = newTestStore(t, home)
+	if err := store.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	reread := newTestStore(t, home)
+	if !reread.Initialized() || len(reread.Runs()) != 0 {
+		t.Errorf("initialized = %v with %d runs, want initialized without runs", reread.Initialized(), len(reread.Runs()))
+	}
+
+	if err := reread.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if newTestStore(t, home).Initialized() {
+		t.Error("initialized after reset")
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"encoding/json"
+	"path/filepath"
+	"os"
+	"grm/cache"
+)
+
+const (
+	// Concurrent requests per download host, package registries and CDNs throttle aggressive clients
+	verifyHostLimit = 4
+	verifyTimeout   = 10 * time.Second
+
+	// Published downloads rarely disappear, missing ones might be published any minute
+	verifiedTtl   = 7 * 24 * time.Hour
+	unverifiedTtl = time.Hour
+)
+
+var verifyModes = []string{"off", "warn", "require"}
+
+// The result of verifying a download url, unverified urls carry the reason, e.g. "HTTP 404" or "timeout"
+type verification struct {
+	Verified bool      `json:"verified"`
+	Reason   string    `json:"reason,omitempty"`
+	Checked  time.Time `json:"checked"`
+}
+
+// Verifies download urls using HEAD requests, falling back to a ranged GET for hosts not supporting
+// HEAD. Results are cached across runs, requests to the same host are limited.
+type downloadVerifier struct {
+	mutex   sync.Mutex
+	hosts   map[string]chan struct{}
+	path    string
+	results map[string]*verification
+	cached  bool
+}
+
+func isValidVerifyMode(mode string) bool {
+	for _, m := range verifyModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Creates the verifier and loads the results of previous runs, unless the cache is disabled
+func newDownloadVerifier(homeDir string, cached bool) *downloadVerifier {
+	verifier := &downloadVerifier{
+		hosts:   make(map[string]chan struct{}),
+		path:    filepath.Join(cache.CacheDir(homeDir), "downloads.json"),
+		results: make(map[string]*verification),
+		cached:  cached,
+	}
+
+	if cached {
+		if data, err := ioutil.ReadFile(verifier.path); err == nil {
+			json.Unmarshal(data, &verifier.results)
+		}
+	}
+	return verifier
+}
+
+func (v *downloadVerifier) verify(httpClient *http.Client, downloadUrl string) *verification {
+	if result := v.cachedResult(downloadUrl); result != nil {
+		return result
+	}
+
+	u, err := url.Parse(downloadUrl)
+	if err != nil || u.Host == "" {
+		return v.store(downloadUrl, &verification{Reason: "invalid url"})
+	}
+
+	slot := v.hostSlot(u.Host)
+	slot <- struct{}{}
+	defer func() { <-slot }()
+
+	status, err := verifyRequest(httpClient, "HEAD", downloadUrl)
+	if err == nil && status != http.StatusNotFound && status != http.StatusGone && !isSuccess(status) {
+		// Some hosts (e.g. pre-signed storage urls) reject HEAD requests, try to read the first byte instead
+		status, err = verifyRequest(httpClient, "GET", downloadUrl)
+	}
+
+	switch {
+	case err != nil:
+		return v.store(downloadUrl, &verification{Reason: verificationReason(err)})
+	case !isSuccess(status):
+		return v.store(downloadUrl, &verification{Reason: fmt.Sprintf("HTTP %d", status)})
+	}
+	return v.store(downloadUrl, &verification{Verified: true})
+}
+
+// Writes the results to the cache, expired results are dropped
+func (v *downloadVerifier) save() error {
+	if !v.cached {
+		return nil
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for downloadUrl, result := range v.results {
+		if result.expired() {
+			delete(v.results, downloadUrl)
+		}
+	}
+
+	data, err := json.Marshal(v.results)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return err
+	}
+
+	tmp := v.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, v.path)
+}
+
+func (v *downloadVerifier) cachedResult(downloadUrl string) *verification {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if result, ok := v.results[downloadUrl]; ok && !result.expired() {
+		return result
+	}
+	return nil
+}
+
+func (v *downloadVerifier) store(downloadUrl string, result *verification) *verification {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	result.Checked = time.Now()
+	v.results[downloadUrl] = result
+	return result
+}
+
+func (v *downloadVerifier) hostSlot(host string) chan struct{} {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	slot, ok := v.hosts[host]
+	if !ok {
+		slot = make(chan struct{}, verifyHostLimit)
+		v.hosts[host] = slot
+	}
+	return slot
+}
+
+func (r *verification) expired() bool {
+	ttl := unverifiedTtl
+	if r.Verified {
+		ttl = verifiedTtl
+	}
+	return time.Since(r.Checked) > ttl
+}
+
+// Requests the url without reading more than the first byte, redirects are followed
+func verifyRequest(httpClient *http.Client, method, downloadUrl string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	request, err := http.NewRequest(method, downloadUrl, nil)
+	if err != nil {
+		return 0, err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("User-Agent", "github-release-monitor")
+	if method == "GET" {
+		request.Header.Set("Range", "bytes=0-0")
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	io.CopyN(ioutil.Discard, response.Body, 1024)
+	response.Body.Close()
+	return response.StatusCode, nil
+}
+
+// Ranged requests answer with 206, hosts ignoring the range with 200
+func isSuccess(status int) bool {
+	return status >= 200 && status < 300
+}
+
+func verificationReason(err error) string {
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return "timeout"
+	}
+
+	if e, ok := err.(*url.Error); ok {
+		err = e.Err
+	}
+	if err == context.DeadlineExceeded {
+		return "timeout"
+	}
+	if _, ok := err.(*net.DNSError); ok {
+		return "unknown host"
+	}
+	if e, ok := err.(*net.OpError); ok {
+		if _, ok := e.Err.(*net.DNSError); ok {
+			return "unknown host"
+		}
+	}
+
+	message := err.Error()
+	if strings.Contains(message, "x509:") || strings.Contains(message, "tls:") {
+		return "TLS error"
+	}
+	if strings.Contains(message, "connection refused") {
+		return "connection refused"
+	}
+	return "connection error"
+}
+
+// Verifies the download urls of all reported releases. Unverified urls are kept with their reason,
+// unless verification is required, in which case they are removed and reported as errors.
+func verifyReleaseDownloads(scans []*definitionScan, verifier *downloadVerifier, require bool, scanErrs *scanErrors) {
+	tasks := new(sync.WaitGroup)
+	for _, scan := range scans {
+		for _, rep := range scan.repositories {
+			for _, rel := range rep.releases {
+				for _, link := range rel.downloadLinks {
+					tasks.Add(1)
+					go func(scan *definitionScan, rep *repository, rel *release, link *downloadLink) {
+						defer tasks.Done()
+						link.verification = verifier.verify(scan.resolvers.httpClient, link.url)
+						if require && !link.verification.Verified {
+							scanErrs.reportPartial(repositoryFullName(rep), []error{fmt.Errorf("release %s: %s url %s unverified: %s",
+								rel.tag, link.name, link.url, link.verification.Reason)})
+						}
+					}(scan, rep, rel, link)
+				}
+
+				if rel.downloadUrl == "" {
+					continue
+				}
+
+				tasks.Add(1)
+				go func(scan *definitionScan, rep *repository, rel *release) {
+					defer tasks.Done()
+					rel.downloadVerification = verifier.verify(scan.resolvers.httpClient, rel.downloadUrl)
+					if require && !rel.downloadVerification.Verified {
+						scanErrs.reportPartial(repositoryFullName(rep), []error{fmt.Errorf("release %s: download url %s unverified: %s",
+							rel.tag, rel.downloadUrl, rel.downloadVerification.Reason)})
+						rel.downloadUrl = ""
+						rel.downloadPageUrl = ""
+					}
+				}(scan, rep, rel)
+			}
+		}
+	}
+	tasks.Wait()
+
+	if require {
+		for _, scan := range scans {
+			for _, rep := range scan.repositories {
+				for _, rel := range rep.releases {
+					links := make([]*downloadLink, 0, len(rel.downloadLinks))
+					for _, link := range rel.downloadLinks {
+						if link.verification.Verified {
+							links = append(links, link)
+						}
+					}
+					rel.downloadLinks = links
+				}
+			}
+		}
+	}
+}
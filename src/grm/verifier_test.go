@@ -0,0 +1,213 @@
+package main
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"time"
+	"sync"
+	"io/ioutil"
+	"os"
+	"fmt"
+	"strings"
+)
+
+// Serves downloads by path: /file answers HEAD and GET, /no-head rejects HEAD, /slow answers late,
+// everything else is missing. Counts the requests and the concurrent requests.
+type testDownloadServer struct {
+	*httptest.Server
+	mutex          sync.Mutex
+	requests       int
+	inFlight       int
+	maxInFlight    int
+	rangedFallback bool
+}
+
+func newTestDownloadServer(tls bool) *testDownloadServer {
+	s := &testDownloadServer{}
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		s.mutex.Lock()
+		s.requests++
+		s.inFlight++
+		if s.inFlight > s.maxInFlight {
+			s.maxInFlight = s.inFlight
+		}
+		s.mutex.Unlock()
+		defer func() {
+			s.mutex.Lock()
+			s.inFlight--
+			s.mutex.Unlock()
+		}()
+
+		switch request.URL.Path {
+		case "/file":
+			writer.WriteHeader(http.StatusOK)
+		case "/no-head":
+			if request.Method == "HEAD" {
+				writer.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			s.mutex.Lock()
+			s.rangedFallback = request.Header.Get("Range") == "bytes=0-0"
+			s.mutex.Unlock()
+			writer.WriteHeader(http.StatusPartialContent)
+			writer.Write([]byte("x"))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			writer.WriteHeader(http.StatusOK)
+		default:
+			if strings.HasPrefix(request.URL.Path, "/parallel/") {
+				time.Sleep(50 * time.Millisecond)
+				writer.WriteHeader(http.StatusOK)
+				return
+			}
+			http.NotFound(writer, request)
+		}
+	})
+	if tls {
+		s.Server = httptest.NewTLSServer(handler)
+	} else {
+		s.Server = httptest.NewServer(handler)
+	}
+	return s
+}
+
+func TestVerifyReasons(t *testing.T) {
+	server := newTestDownloadServer(false)
+	defer server.Close()
+	tlsServer := newTestDownloadServer(true)
+	defer tlsServer.Close()
+	closed := newTestDownloadServer(false)
+	closed.Close()
+
+	// Only the slow host runs into the timeout of the client
+	client := &http.Client{Timeout: 5 * time.Second}
+	impatient := &http.Client{Timeout: 100 * time.Millisecond}
+	tests := []struct {
+		name     string
+		client   *http.Client
+		url      string
+		verified bool
+		reason   string
+	}{
+		{"available", client, server.URL + "/file", true, ""},
+		{"missing", client, server.URL + "/missing", false, "HTTP 404"},
+		{"ranged GET without HEAD", client, server.URL + "/no-head", true, ""},
+		{"slow host", impatient, server.URL + "/slow", false, "timeout"},
+		// The certificate of the test server isn't trusted by the client
+		{"untrusted certificate", client, tlsServer.URL + "/file", false, "TLS error"},
+		{"connection refused", client, closed.URL + "/file", false, "connection refused"},
+		{"invalid url", client, "not a url", false, "invalid url"},
+	}
+
+	verifier := newDownloadVerifier(os.TempDir(), false)
+	for _, test := range tests {
+		result := verifier.verify(test.client, test.url)
+		if result.Verified != test.verified || result.Reason != test.reason {
+			t.Errorf("%s: verification = %v %q, want %v %q", test.name, result.Verified, result.Reason, test.verified, test.reason)
+		}
+	}
+	if !server.rangedFallback {
+		t.Error("fallback GET requested more than the first byte")
+	}
+}
+
+func TestVerifyHostLimit(t *testing.T) {
+	server := newTestDownloadServer(false)
+	defer server.Close()
+
+	verifier := newDownloadVerifier(os.TempDir(), false)
+	tasks := new(sync.WaitGroup)
+	for i := 0; i < 3*verifyHostLimit; i++ {
+		tasks.Add(1)
+		go func(i int) {
+			defer tasks.Done()
+			verifier.verify(http.DefaultClient, fmt.Sprintf("%s/parallel/%d", server.URL, i))
+		}(i)
+	}
+	tasks.Wait()
+
+	if server.maxInFlight > verifyHostLimit || server.requests != 3*verifyHostLimit {
+		t.Errorf("%d requests, %d concurrent, want %d requests with at most %d concurrent",
+			server.requests, server.maxInFlight, 3*verifyHostLimit, verifyHostLimit)
+	}
+}
+
+func TestVerifyCachedResults(t *testing.T) {
+	server := newTestDownloadServer(false)
+	defer server.Close()
+	home, err := ioutil.TempDir("", "grm-verifier-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	tests := []struct {
+		name     string
+		path     string
+		age      time.Duration
+		requests int
+	}{
+		{"fresh verified", "/file", time.Hour, 0},
+		{"expired verified", "/file", verifiedTtl + time.Minute, 1},
+		{"fresh unverified", "/missing", 30 * time.Minute, 0},
+		{"expired unverified", "/missing", unverifiedTtl + time.Minute, 1},
+	}
+
+	for _, test := range tests {
+		downloadUrl := server.URL + test.path
+		verifier := newDownloadVerifier(home, true)
+		verifier.verify(http.DefaultClient, downloadUrl)
+		verifier.results[downloadUrl].Checked = time.Now().Add(-test.age)
+		if err := verifier.save(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Results are reused by later runs until they expire
+		requests := server.requests
+		newDownloadVerifier(home, true).verify(http.DefaultClient, downloadUrl)
+		if server.requests-requests != test.requests {
+			t.Errorf("%s: %d requests, want %d", test.name, server.requests-requests, test.requests)
+		}
+	}
+}
+
+func TestVerifyReleaseDownloadsRequire(t *testing.T) {
+	server := newTestDownloadServer(false)
+	defer server.Close()
+
+	for _, require := range []bool{false, true} {
+		rel := &release{
+			tag:             "v1.0.0",
+			downloadUrl:     server.URL + "/missing",
+			downloadPageUrl: "https://example.com/v1.0.0",
+			downloadLinks: []*downloadLink{
+				{name: "docs", url: server.URL + "/file"},
+				{name: "javadoc", url: server.URL + "/missing"},
+			},
+		}
+		scan := &definitionScan{
+			resolvers:    &downloadResolvers{httpClient: http.DefaultClient},
+			repositories: []*repository{{owner: "noctarius", name: "example", releases: []*release{rel}}},
+		}
+		scanErrs := newScanErrors(0)
+		verifyReleaseDownloads([]*definitionScan{scan}, newDownloadVerifier(os.TempDir(), false), require, scanErrs)
+
+		links, errs, downloadUrl := 2, 0, server.URL+"/missing"
+		if require {
+			links, errs, downloadUrl = 1, 2, ""
+		}
+		if len(rel.downloadLinks) != links || len(scanErrs.errors) != errs || rel.downloadUrl != downloadUrl {
+			t.Errorf("require %v: %d links, %d errors, download url %q, want %d, %d, %q", require,
+				len(rel.downloadLinks), len(scanErrs.errors), rel.downloadUrl, links, errs, downloadUrl)
+		}
+		if rel.downloadVerification == nil || rel.downloadVerification.Reason != "HTTP 404" {
+			t.Errorf("require %v: download verification = %+v", require, rel.downloadVerification)
+		}
+		for _, e := range scanErrs.errors {
+			if !e.partial || e.repository != "noctarius/example" {
+				t.Errorf("require %v: error %+v, want partial error of noctarius/example", require, e)
+			}
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package version
+
+import (
+	"strings"
+	"strconv"
+	"fmt"
+)
+
+type Bump int
+
+const (
+	Patch Bump = iota
+	Minor
+	Major
+)
+
+var bumpNames = map[Bump]string{
+	Patch: "patch",
+	Minor: "minor",
+	Major: "major",
+}
+
+// Qualifiers of final releases, e.g. 1.2.0.Final or 1.2.0-RELEASE
+var releaseQualifiers = map[string]bool{
+	"final":   true,
+	"ga":      true,
+	"release": true,
+}
+
+// Separators between a project name and the version, e.g. example-1.2.0 or release/1.2.0
+const prefixSeparators = "-_/ "
+
+type Version struct {
+	Original   string
+	Numbers    []int
+	Prerelease string
+	Build      string
+}
+
+// Parses a semantic version, leniently accepting common deviations like a "v" or project name
+// prefix, missing minor or patch numbers, more than three numbers and qualifiers like ".Final"
+func Parse(value string) (*Version, error) {
+	start := versionStart(value)
+	if start == -1 {
+		return nil, fmt.Errorf("no version number found: %s", value)
+	}
+
+	remainder := value[start:]
+
+	build := ""
+	if i := strings.Index(remainder, "+"); i != -1 {
+		build = remainder[i+1:]
+		remainder = remainder[:i]
+	}
+
+	numbers := make([]int, 0, 3)
+	for {
+		end := 0
+		for end < len(remainder) && remainder[end] >= '0' && remainder[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+
+		number, err := strconv.Atoi(remainder[:end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid version number %s: %v", value, err)
+		}
+		numbers = append(numbers, number)
+		remainder = remainder[end:]
+
+		// A dot followed by a digit continues the version number, anything else starts the qualifier
+		if len(remainder) > 1 && remainder[0] == '.' && remainder[1] >= '0' && remainder[1] <= '9' {
+			remainder = remainder[1:]
+			continue
+		}
+		break
+	}
+
+	prerelease := strings.TrimLeft(remainder, ".-_")
+	if releaseQualifiers[strings.ToLower(prerelease)] {
+		prerelease = ""
+	}
+
+	return &Version{
+		Original:   value,
+		Numbers:    numbers,
+		Prerelease: prerelease,
+		Build:      build,
+	}, nil
+}
+
+// Finds the first number at the start of the value or following a separated prefix, e.g. "v" in
+// v1.2.0 or "example-" in example-1.2.0. Digits within a name, like in k8s-1.2.0, are skipped.
+func versionStart(value string) int {
+	for i := 0; i < len(value); i++ {
+		if !isDigit(value[i]) {
+			continue
+		}
+		prefix := strings.TrimSuffix(strings.TrimSuffix(value[:i], "v"), "V")
+		if prefix == "" || strings.ContainsAny(prefix[len(prefix)-1:], prefixSeparators) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (v *Version) Major() int {
+	return v.number(0)
+}
+
+func (v *Version) Minor() int {
+	return v.number(1)
+}
+
+func (v *Version) Patch() int {
+	return v.number(2)
+}
+
+func (v *Version) IsPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+func (v *Version) String() string {
+	numbers := make([]string, 0, len(v.Numbers))
+	for _, number := range v.Numbers {
+		numbers = append(numbers, strconv.Itoa(number))
+	}
+
+	value := strings.Join(numbers, ".")
+	if v.Prerelease != "" {
+		value += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		value += "+" + v.Build
+	}
+	return value
+}
+
+// Missing numbers are treated as zero, so that 1.2 equals 1.2.0
+func (v *Version) number(index int) int {
+	if index < len(v.Numbers) {
+		return v.Numbers[index]
+	}
+	return 0
+}
+
+// Compares two versions with semantic versioning precedence, returns -1, 0 or 1.
+// Build metadata is ignored, pre-releases have a lower precedence than the release.
+func Compare(a, b *Version) int {
+	count := len(a.Numbers)
+	if len(b.Numbers) > count {
+		count = len(b.Numbers)
+	}
+	for i := 0; i < count; i++ {
+		if c := compareInt(a.number(i), b.number(i)); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case a.Prerelease == b.Prerelease:
+		return 0
+	case a.Prerelease == "":
+		return 1
+	case b.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(a.Prerelease, b.Prerelease)
+}
+
+// Classifies the increment from the previous to the current version, a missing
+// previous version (the first release) is a major increment
+func Classify(previous, current *Version) Bump {
+	switch {
+	case previous == nil || current.Major() != previous.Major():
+		return Major
+	case current.Minor() != previous.Minor():
+		return Minor
+	}
+	return Patch
+}
+
+func ParseBump(value string) (Bump, error) {
+	for bump, name := range bumpNames {
+		if name == strings.ToLower(value) {
+			return bump, nil
+		}
+	}
+	return Patch, fmt.Errorf("unknown version bump: %s", value)
+}
+
+func (b Bump) String() string {
+	return bumpNames[b]
+}
+
+// Compares dot separated pre-release identifiers, lenient identifiers like "RC10" are compared
+// by their alphabetic and numeric parts, so that "rc2" sorts before "rc10"
+func comparePrerelease(a, b string) int {
+	identifiersA := strings.FieldsFunc(strings.ToLower(a), isSeparator)
+	identifiersB := strings.FieldsFunc(strings.ToLower(b), isSeparator)
+
+	for i := 0; i < len(identifiersA) && i < len(identifiersB); i++ {
+		partsA := splitIdentifier(identifiersA[i])
+		partsB := splitIdentifier(identifiersB[i])
+
+		for j := 0; j < len(partsA) && j < len(partsB); j++ {
+			if c := compareIdentifier(partsA[j], partsB[j]); c != 0 {
+				return c
+			}
+		}
+		if c := compareInt(len(partsA), len(partsB)); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(identifiersA), len(identifiersB))
+}
+
+// Numeric identifiers have a lower precedence than alphanumeric ones
+func compareIdentifier(a, b string) int {
+	numberA, errA := strconv.Atoi(a)
+	numberB, errB := strconv.Atoi(b)
+
+	switch {
+	case errA == nil && errB == nil:
+		return compareInt(numberA, numberB)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// Splits an identifier into alternating alphabetic and numeric parts
+func splitIdentifier(identifier string) []string {
+	parts := make([]string, 0, 2)
+	start := 0
+	for i := 1; i <= len(identifier); i++ {
+		if i == len(identifier) || isDigit(identifier[i]) != isDigit(identifier[i-1]) {
+			parts = append(parts, identifier[start:i])
+			start = i
+		}
+	}
+	return parts
+}
+
+func isSeparator(r rune) bool {
+	return r == '.' || r == '-' || r == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
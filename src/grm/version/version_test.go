@@ -0,0 +1,124 @@
+package version
+
+import (
+	"testing"
+	"reflect"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value      string
+		numbers    []int
+		prerelease string
+		build      string
+		err        bool
+	}{
+		{"1.2.3", []int{1, 2, 3}, "", "", false},
+		{"v1.2", []int{1, 2}, "", "", false},
+		{"example-2.0.0-rc.1", []int{2, 0, 0}, "rc.1", "", false},
+		{"1.2.3.4", []int{1, 2, 3, 4}, "", "", false},
+		{"3.0.0.Final", []int{3, 0, 0}, "", "", false},
+		{"5.1.0-RELEASE", []int{5, 1, 0}, "", "", false},
+		{"1.0.0.CR2", []int{1, 0, 0}, "CR2", "", false},
+		{"1.0.0-beta+exp.sha.5114f85", []int{1, 0, 0}, "beta", "exp.sha.5114f85", false},
+		{"k8s-1.2.0", []int{1, 2, 0}, "", "", false},
+		{"log4j-2.0", []int{2, 0}, "", "", false},
+		{"example-v3.1", []int{3, 1}, "", "", false},
+		{"release/1.4.0", []int{1, 4, 0}, "", "", false},
+		{"Version 2.1", []int{2, 1}, "", "", false},
+		{"latest", nil, "", "", true},
+		{"log4j", nil, "", "", true},
+		{"rc1", nil, "", "", true},
+	}
+
+	for _, test := range tests {
+		v, err := Parse(test.value)
+		if (err != nil) != test.err {
+			t.Errorf("%q: error = %v, want error %v", test.value, err, test.err)
+			continue
+		}
+		if test.err {
+			continue
+		}
+		if !reflect.DeepEqual(v.Numbers, test.numbers) || v.Prerelease != test.prerelease || v.Build != test.build {
+			t.Errorf("%q: version = %v %q %q, want %v %q %q", test.value,
+				v.Numbers, v.Prerelease, v.Build, test.numbers, test.prerelease, test.build)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"v1.10.0", "v1.9.0", 1},
+		{"1.2.3", "1.2.4", -1},
+		{"2.0.0", "2.0.0-rc.1", 1},
+		{"2.0.0-alpha", "2.0.0-beta", -1},
+		{"2.0.0-rc2", "2.0.0-rc10", -1},
+		{"2.0.0-rc.1", "2.0.0-rc.1.1", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+		{"1.0.0+build.1", "1.0.0+build.2", 0},
+		{"3.0.0.Final", "3.0.0", 0},
+	}
+
+	for _, test := range tests {
+		a, _ := Parse(test.a)
+		b, _ := Parse(test.b)
+		if got := Compare(a, b); got != test.want {
+			t.Errorf("Compare(%s, %s) = %d, want %d", test.a, test.b, got, test.want)
+		}
+		if got := Compare(b, a); got != -test.want {
+			t.Errorf("Compare(%s, %s) = %d, want %d", test.b, test.a, got, -test.want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		previous, current string
+		want              Bump
+	}{
+		{"", "1.0.0", Major},
+		{"1.4.2", "2.0.0", Major},
+		{"1.4.2", "1.5.0", Minor},
+		{"1.4", "1.5", Minor},
+		{"1.4.2", "1.4.3", Patch},
+		{"1.4.2", "1.4.2.1", Patch},
+		{"0.9.0", "1.0.0-rc.1", Major},
+	}
+
+	for _, test := range tests {
+		var previous *Version
+		if test.previous != "" {
+			previous, _ = Parse(test.previous)
+		}
+		current, _ := Parse(test.current)
+		if got := Classify(previous, current); got != test.want {
+			t.Errorf("Classify(%q, %q) = %s, want %s", test.previous, test.current, got, test.want)
+		}
+	}
+}
+
+func TestParseBump(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Bump
+		err   bool
+	}{
+		{"patch", Patch, false},
+		{"Minor", Minor, false},
+		{"MAJOR", Major, false},
+		{"breaking", Patch, true},
+	}
+
+	for _, test := range tests {
+		bump, err := ParseBump(test.value)
+		if (err != nil) != test.err || bump != test.want {
+			t.Errorf("%q: bump = %s, %v, want %s, error %v", test.value, bump, err, test.want, test.err)
+		}
+	}
+}